@@ -24,16 +24,23 @@ type AnalysisListing struct {
 	Analyses []interface{} `json:"analyses"`
 }
 
+// AnalysisAPI is a client for the analysis listings in the apps service. It's
+// safe for concurrent use; request URLs are derived from the base URL without
+// modifying it.
 type AnalysisAPI struct {
 	appsURL *url.URL
 }
 
+// NewAnalysisAPI returns a new *AnalysisAPI. It keeps its own copy of the URL.
 func NewAnalysisAPI(appsURL *url.URL) *AnalysisAPI {
+	u := *appsURL
 	return &AnalysisAPI{
-		appsURL: appsURL,
+		appsURL: &u,
 	}
 }
 
+// fixUsername strips the domain from a canonical username, since the other
+// services expect bare usernames.
 func fixUsername(username string) string {
 	parts := strings.Split(username, "@")
 	if len(parts) > 0 {
@@ -80,21 +87,23 @@ func (a *AnalysisAPI) RunningAnalyses(ctx context.Context, username string, limi
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, newUpstreamRequestError(AppsService, fullURL.String(), err)
 	}
 	defer resp.Body.Close()
 
 	log.Debugf("done getting running analyses from %s", fullURL.String())
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status code from %s was %d", fullURL.String(), resp.StatusCode)
-	}
-
 	b, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		upstreamErr := newUpstreamError(AppsService, fullURL.String(), resp.StatusCode, b)
+		upstreamErr.NamedResource = true
+		return nil, upstreamErr
+	}
+
 	var data AnalysisListing
 	if err = json.Unmarshal(b, &data); err != nil {
 		return nil, err
@@ -140,7 +149,7 @@ func (a *AnalysisAPI) RecentAnalyses(ctx context.Context, username string, limit
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, newUpstreamRequestError(AppsService, fullURL.String(), err)
 	}
 	defer resp.Body.Close()
 
@@ -152,8 +161,9 @@ func (a *AnalysisAPI) RecentAnalyses(ctx context.Context, username string, limit
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		msg := string(b)
-		return nil, fmt.Errorf("url %s; status code %d; msg: %s", fullURL.String(), resp.StatusCode, msg)
+		upstreamErr := newUpstreamError(AppsService, fullURL.String(), resp.StatusCode, b)
+		upstreamErr.NamedResource = true
+		return nil, upstreamErr
 	}
 
 	var data AnalysisListing
@@ -0,0 +1,351 @@
+package apis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cyverse-de/dashboard-aggregator/config"
+)
+
+const (
+	testAppID          = "6f1e6f2c-3a0b-4c4e-9f0e-2a1d5c3b4a59"
+	testPublicGroupID  = "public-group"
+	testAppExposerUser = "de-service"
+	testAttribute      = "ui_location"
+	testValue          = "dashboard"
+	testAnalysesLimit  = 5
+	concurrentCallers  = 50
+)
+
+// expectQuery reports an error if the request's query parameters aren't
+// exactly the expected ones.
+func expectQuery(t *testing.T, r *http.Request, expected url.Values) {
+	t.Helper()
+	if got := r.URL.Query(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("%s %s: expected query %v, got %v", r.Method, r.URL.Path, expected, got)
+	}
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Error(err)
+	}
+}
+
+// newTestServer returns a server that stands in for app-exposer, the apps
+// service, the metadata service, and the permissions service. Each response
+// echoes the username from the request, so callers can tell whether they got
+// the response to their own request.
+func newTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+
+	instantLaunch := func(addedBy string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":              "il-" + addedBy,
+			"quick_launch_id": "ql-" + addedBy,
+			"added_by":        addedBy,
+			"quick_launch": map[string]interface{}{
+				"id":     "ql-" + addedBy,
+				"app_id": testAppID,
+			},
+		}
+	}
+
+	mux.HandleFunc("/app-exposer/instantlaunches/metadata/full", func(w http.ResponseWriter, r *http.Request) {
+		expectQuery(t, r, url.Values{
+			"user":      {testAppExposerUser},
+			"attribute": {testAttribute},
+			"value":     {testValue},
+		})
+		writeJSON(t, w, []interface{}{instantLaunch("metadata")})
+	})
+
+	mux.HandleFunc("/app-exposer/instantlaunches/full", func(w http.ResponseWriter, r *http.Request) {
+		user := r.URL.Query().Get("user")
+		expectQuery(t, r, url.Values{"user": {user}})
+		writeJSON(t, w, []interface{}{instantLaunch(user), instantLaunch("other")})
+	})
+
+	mux.HandleFunc("/apps/analyses", func(w http.ResponseWriter, r *http.Request) {
+		user := r.URL.Query().Get("user")
+		expected := url.Values{
+			"limit": {fmt.Sprint(testAnalysesLimit)},
+			"user":  {user},
+		}
+		if filter := r.URL.Query().Get("filter"); filter != "" {
+			expected.Set("filter", `[{"field":"status","value":"Running"}]`)
+		} else {
+			expected.Set("sort-field", "startdate")
+			expected.Set("sort-dir", "DESC")
+		}
+		expectQuery(t, r, expected)
+		writeJSON(t, w, AnalysisListing{Analyses: []interface{}{user}})
+	})
+
+	mux.HandleFunc("/metadata/avus/filter-targets", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected a POST to %s, got a %s", r.URL.Path, r.Method)
+		}
+		user := r.URL.Query().Get("user")
+		expectQuery(t, r, url.Values{"user": {user}})
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		writeJSON(t, w, TargetIDs{TargetIDs: []string{user}})
+	})
+
+	mux.HandleFunc("/permissions/permissions/abbreviated/subjects/group/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/permissions/permissions/abbreviated/subjects/group/"+testPublicGroupID+"/app" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		expectQuery(t, r, url.Values{})
+		writeJSON(t, w, PermissionsResponse{Permissions: []Permission{{ResourceName: testAppID}}})
+	})
+
+	mux.HandleFunc("/permissions/permissions/subjects/user/", func(w http.ResponseWriter, r *http.Request) {
+		user, found := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/permissions/permissions/subjects/user/"), "/app")
+		if !found {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		expectQuery(t, r, url.Values{"lookup": {"true"}})
+
+		permission := func(resource, level, subjectType, subjectID string) FullPermission {
+			return FullPermission{
+				PermissionLevel: level,
+				Resource:        PermissionResource{Name: resource, ResourceType: "app"},
+				Subject:         PermissionSubject{SubjectID: subjectID, SubjectType: subjectType},
+			}
+		}
+		writeJSON(t, w, FullPermissionsResponse{Permissions: []FullPermission{
+			permission("owned-"+user, "own", "user", user),
+			permission("owned-"+user, "read", "group", "some-group"),
+			permission("shared-"+user, "read", "user", user),
+			permission("shared-"+user, "write", "group", "some-group"),
+			permission("public", "read", "group", testPublicGroupID),
+		}})
+	})
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.String())
+		http.NotFound(w, r)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func mustParse(t *testing.T, s string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// TestConcurrentRequests calls each client from many goroutines at once. Run
+// it with -race to catch clients that modify their shared base URLs.
+func TestConcurrentRequests(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	appsURL := mustParse(t, srv.URL+"/apps")
+	metadataURL := mustParse(t, srv.URL+"/metadata")
+	permissionsURL := mustParse(t, srv.URL+"/permissions")
+
+	analysisAPI := NewAnalysisAPI(appsURL)
+	metadataAPI := NewMetadataAPI(metadataURL)
+	permissionsAPI := NewPermissionsAPI(permissionsURL)
+	ilAPI, err := NewInstantLaunchesAPI(&config.ServiceConfiguration{
+		AppExposer: &config.AppExposerConfiguration{
+			URL:                    srv.URL + "/app-exposer",
+			User:                   testAppExposerUser,
+			InstantLaunchAttribute: testAttribute,
+			InstantLaunchValue:     testValue,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseURLs := map[string]*url.URL{
+		"analysis":               analysisAPI.appsURL,
+		"metadata":               metadataAPI.metadataURL,
+		"permissions":            permissionsAPI.permissionsURL,
+		"instant launches base":  ilAPI.baseURL,
+		"instant launches items": ilAPI.appExposerURL,
+		"apps argument":          appsURL,
+		"metadata argument":      metadataURL,
+		"permissions argument":   permissionsURL,
+	}
+	before := make(map[string]string, len(baseURLs))
+	for name, u := range baseURLs {
+		before[name] = u.String()
+	}
+
+	publicGroupID := testPublicGroupID
+
+	var wg sync.WaitGroup
+	for n := 0; n < concurrentCallers; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+
+			user := fmt.Sprintf("user%d", n)
+			username := user + "@iplantcollaborative.org"
+
+			items, err := ilAPI.PullItems(ctx)
+			if err != nil {
+				t.Error(err)
+			} else if len(items) != 1 || items[0].AddedBy != "metadata" {
+				t.Errorf("unexpected instant launches %+v", items)
+			}
+
+			userItems, err := ilAPI.PullUserItems(ctx, username)
+			if err != nil {
+				t.Error(err)
+			} else if len(userItems) != 1 || userItems[0].AddedBy != user {
+				t.Errorf("%s got instant launches %+v", user, userItems)
+			}
+
+			all, err := ilAPI.PullAllItems(ctx)
+			if err != nil {
+				t.Error(err)
+			} else if len(all) != 2 || all[0].AddedBy != testAppExposerUser {
+				t.Errorf("unexpected instant launches %+v", all)
+			}
+
+			recent, err := analysisAPI.RecentAnalyses(ctx, username, testAnalysesLimit)
+			if err != nil {
+				t.Error(err)
+			} else if !reflect.DeepEqual(recent.Analyses, []interface{}{user}) {
+				t.Errorf("%s got recent analyses %v", user, recent.Analyses)
+			}
+
+			running, err := analysisAPI.RunningAnalyses(ctx, username, testAnalysesLimit)
+			if err != nil {
+				t.Error(err)
+			} else if !reflect.DeepEqual(running.Analyses, []interface{}{user}) {
+				t.Errorf("%s got running analyses %v", user, running.Analyses)
+			}
+
+			targetIDs, err := metadataAPI.GetFilteredTargetIDs(ctx, username, []string{"app"}, nil, []string{testAppID})
+			if err != nil {
+				t.Error(err)
+			} else if !reflect.DeepEqual(targetIDs, []string{user}) {
+				t.Errorf("%s got target IDs %v", user, targetIDs)
+			}
+
+			publicIDs, err := permissionsAPI.GetPublicIDS(ctx, &publicGroupID)
+			if err != nil {
+				t.Error(err)
+			} else if !reflect.DeepEqual(publicIDs, []string{testAppID}) {
+				t.Errorf("unexpected public app IDs %v", publicIDs)
+			}
+
+			sharedIDs, err := permissionsAPI.GetSharedAppIDs(ctx, username, &publicGroupID)
+			if err != nil {
+				t.Error(err)
+			} else if !reflect.DeepEqual(sharedIDs, []string{"shared-" + user}) {
+				t.Errorf("%s got shared app IDs %v", user, sharedIDs)
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	for name, u := range baseURLs {
+		if after := u.String(); after != before[name] {
+			t.Errorf("%s URL changed from %s to %s", name, before[name], after)
+		}
+	}
+}
+
+func TestUpstreamErrorsHideDetails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal stack trace", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	metadataAPI := NewMetadataAPI(mustParse(t, srv.URL))
+	_, err := metadataAPI.GetFilteredTargetIDs(context.Background(), "ipcdev@iplantcollaborative.org", []string{"app"}, nil, nil)
+
+	var upstreamErr *UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		t.Fatalf("expected an *UpstreamError, got %v", err)
+	}
+	if status := upstreamErr.HTTPStatus(); status != http.StatusBadGateway {
+		t.Errorf("expected status %d, got %d", http.StatusBadGateway, status)
+	}
+	for _, leaked := range []string{srv.URL, "ipcdev", "stack trace"} {
+		if strings.Contains(upstreamErr.ClientMessage(), leaked) || strings.Contains(fmt.Sprint(upstreamErr.Details()), leaked) {
+			t.Errorf("client-facing error contains %q", leaked)
+		}
+	}
+}
+
+func TestFilterHierarchies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/ontologies/v1/filter" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		expectQuery(t, r, url.Values{"user": {"ipcdev"}, "attr": {"rdf:type", "dc:subject"}})
+
+		var body map[string][]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		expected := map[string][]string{"target-types": {"app"}, "target-ids": {testAppID}}
+		if !reflect.DeepEqual(body, expected) {
+			t.Errorf("expected body %v, got %v", expected, body)
+		}
+
+		writeJSON(t, w, map[string]interface{}{
+			"hierarchies": []interface{}{
+				map[string]interface{}{
+					"iri":   "http://edamontology.org/topic_0003",
+					"label": "Topic",
+					"subclasses": []interface{}{
+						map[string]interface{}{"iri": "http://edamontology.org/topic_0622", "label": "Genomics"},
+					},
+				},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	metadataAPI := NewMetadataAPI(mustParse(t, srv.URL))
+	hierarchies, err := metadataAPI.FilterHierarchies(
+		context.Background(),
+		"ipcdev@iplantcollaborative.org",
+		"v1",
+		[]string{"rdf:type", "dc:subject"},
+		[]string{"app"},
+		[]string{testAppID},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []OntologyClass{{
+		IRI:        "http://edamontology.org/topic_0003",
+		Label:      "Topic",
+		Subclasses: []OntologyClass{{IRI: "http://edamontology.org/topic_0622", Label: "Genomics"}},
+	}}
+	if !reflect.DeepEqual(hierarchies, expected) {
+		t.Errorf("expected hierarchies %+v, got %+v", expected, hierarchies)
+	}
+}
@@ -0,0 +1,126 @@
+package apis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Names of the upstream services used in UpstreamErrors.
+const (
+	AppsService         = "apps"
+	AppExposerService   = "app-exposer"
+	MetadataService     = "metadata"
+	PermissionsService  = "permissions"
+	IplantGroupsService = "iplant-groups"
+)
+
+// maxBodyExcerpt is the maximum number of bytes of an upstream response body
+// that are kept in an UpstreamError.
+const maxBodyExcerpt = 512
+
+// UpstreamError is returned by the API clients when a request to another
+// service either fails outright or returns an unexpected status code. The URL
+// and Body fields may contain usernames and internal details, so they belong
+// in the logs and must not be sent back to our own callers.
+type UpstreamError struct {
+	Service    string
+	URL        string
+	StatusCode int
+	Body       string
+	Err        error
+
+	// NamedResource is set when the upstream resource is the one our caller
+	// asked for, such as the user named in the request path. Only then is an
+	// upstream 404 passed through as a 404.
+	NamedResource bool
+}
+
+func newUpstreamError(service, url string, statusCode int, body []byte) *UpstreamError {
+	excerpt := string(body)
+	if len(excerpt) > maxBodyExcerpt {
+		excerpt = excerpt[:maxBodyExcerpt]
+	}
+	return &UpstreamError{
+		Service:    service,
+		URL:        url,
+		StatusCode: statusCode,
+		Body:       excerpt,
+	}
+}
+
+func newUpstreamRequestError(service, url string, err error) *UpstreamError {
+	return &UpstreamError{
+		Service: service,
+		URL:     url,
+		Err:     err,
+	}
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("service %s; url %s; error %s", e.Service, e.URL, e.Err)
+	}
+	return fmt.Sprintf("service %s; url %s; status code %d; msg %s", e.Service, e.URL, e.StatusCode, e.Body)
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout returns true if the request to the upstream service timed out,
+// either on our end or on a gateway in front of the service.
+func (e *UpstreamError) Timeout() bool {
+	if e.StatusCode == http.StatusGatewayTimeout {
+		return true
+	}
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(e.Err, &netErr) && netErr.Timeout()
+}
+
+// HTTPStatus returns the status code that should be sent to our own callers.
+// Not-found responses are passed through when they refer to the resource the
+// caller named, timeouts become a 504, and everything else (including upstream
+// bad-request responses, which mean we built a bad request) is treated as a
+// bad gateway.
+func (e *UpstreamError) HTTPStatus() int {
+	switch {
+	case e.StatusCode == http.StatusNotFound && e.NamedResource:
+		return e.StatusCode
+	case e.Timeout():
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// ClientMessage returns an error message that is safe to send to our own
+// callers. It names the service but leaves out URLs and response bodies.
+func (e *UpstreamError) ClientMessage() string {
+	switch {
+	case e.Timeout():
+		return fmt.Sprintf("request to the %s service timed out", e.Service)
+	case e.StatusCode != 0:
+		return fmt.Sprintf("the %s service returned status code %d", e.Service, e.StatusCode)
+	default:
+		return fmt.Sprintf("request to the %s service failed", e.Service)
+	}
+}
+
+// Details returns machine-readable information about the error, suitable for
+// the details field of an error response. Only the service name and upstream
+// status are included.
+func (e *UpstreamError) Details() map[string]interface{} {
+	details := map[string]interface{}{
+		"service": e.Service,
+	}
+	if e.StatusCode != 0 {
+		details["upstream_status"] = e.StatusCode
+	}
+	return details
+}
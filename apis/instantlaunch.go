@@ -0,0 +1,101 @@
+package apis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// InstantLaunchAVU is a metadata AVU attached to an instant launch.
+type InstantLaunchAVU struct {
+	ID        string `json:"id,omitempty"`
+	Attribute string `json:"attr"`
+	Value     string `json:"value"`
+	Unit      string `json:"unit"`
+}
+
+// QuickLaunch is the saved app submission that an instant launch starts.
+type QuickLaunch struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Creator     string          `json:"creator"`
+	AppID       string          `json:"app_id"`
+	IsPublic    bool            `json:"is_public"`
+	Submission  json.RawMessage `json:"submission,omitempty"`
+}
+
+// InstantLaunchApp describes the app started by an instant launch. It's
+// filled in from the database rather than by app-exposer.
+type InstantLaunchApp struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsFavorite  bool   `json:"is_favorite"`
+}
+
+// InstantLaunch is an instant launch as listed by app-exposer.
+type InstantLaunch struct {
+	ID            string             `json:"id"`
+	QuickLaunchID string             `json:"quick_launch_id"`
+	AddedBy       string             `json:"added_by"`
+	AddedOn       string             `json:"added_on"`
+	Metadata      []InstantLaunchAVU `json:"metadata,omitempty"`
+	QuickLaunch   QuickLaunch        `json:"quick_launch"`
+	App           *InstantLaunchApp  `json:"app,omitempty"`
+	LaunchURL     string             `json:"launch_url,omitempty"`
+}
+
+// Validate returns an error if the instant launch is missing fields needed to
+// display or launch it.
+func (i *InstantLaunch) Validate() error {
+	switch {
+	case i.ID == "":
+		return errors.New("instant launch is missing its id")
+	case i.QuickLaunchID == "":
+		return errors.New("instant launch is missing its quick_launch_id")
+	case i.QuickLaunch.ID != "" && i.QuickLaunch.ID != i.QuickLaunchID:
+		return errors.New("instant launch quick_launch_id does not match its quick launch")
+	case i.QuickLaunch.AppID == "":
+		return errors.New("instant launch quick launch is missing its app_id")
+	}
+	if _, err := uuid.Parse(i.QuickLaunch.AppID); err != nil {
+		return fmt.Errorf("instant launch quick launch app_id is invalid: %w", err)
+	}
+	return nil
+}
+
+// AddedByUser returns true if the instant launch was added by the user. The
+// username may be qualified with the user domain or not.
+func (i *InstantLaunch) AddedByUser(username string) bool {
+	return fixUsername(i.AddedBy) == fixUsername(username)
+}
+
+// parseInstantLaunches decodes a JSON list of instant launches. Items that
+// can't be decoded or aren't valid are logged and left out.
+func parseInstantLaunches(data []byte) ([]InstantLaunch, error) {
+	log := log.WithField("context", "parsing instant launches")
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	items := make([]InstantLaunch, 0, len(raw))
+	for index, r := range raw {
+		var item InstantLaunch
+		if err := json.Unmarshal(r, &item); err != nil {
+			log.Warnf("dropping instant launch %d: %s", index, err)
+			continue
+		}
+		if err := item.Validate(); err != nil {
+			log.Warnf("dropping instant launch %d: %s", index, err)
+			continue
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
@@ -2,17 +2,20 @@ package apis
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 
 	"github.com/cyverse-de/dashboard-aggregator/config"
+	"github.com/samber/lo"
 	"go.opentelemetry.io/otel"
 )
 
+// InstantLaunchesAPI is a client for the instant launches in app-exposer. It's
+// safe for concurrent use; request URLs are derived from the base URLs without
+// modifying them.
 type InstantLaunchesAPI struct {
+	baseURL        *url.URL
 	appExposerURL  *url.URL
 	appExposerUser string
 	attribute      string
@@ -20,31 +23,23 @@ type InstantLaunchesAPI struct {
 }
 
 func NewInstantLaunchesAPI(config *config.ServiceConfiguration) (*InstantLaunchesAPI, error) {
-	u, err := url.Parse(config.AppExposer.URL)
+	base, err := url.Parse(config.AppExposer.URL)
 	if err != nil {
 		return nil, err
 	}
-	u = u.JoinPath("instantlaunches", "metadata", "full")
+	u := base.JoinPath("instantlaunches", "metadata", "full")
 	return &InstantLaunchesAPI{
+		baseURL:        base,
 		appExposerURL:  u,
 		appExposerUser: config.AppExposer.User,
-		attribute:      "ui_location",
-		value:          "dashboard",
+		attribute:      config.AppExposer.InstantLaunchAttribute,
+		value:          config.AppExposer.InstantLaunchValue,
 	}, nil
 }
 
-func (i *InstantLaunchesAPI) PullItems(ctx context.Context) ([]map[string]interface{}, error) {
-	ctx, span := otel.Tracer(otelName).Start(ctx, "PullItems")
-	defer span.End()
-
-	u := i.appExposerURL
-
-	q := u.Query()
-	q.Set("user", i.appExposerUser)
-	q.Set("attribute", i.attribute)
-	q.Set("value", i.value)
-	u.RawQuery = q.Encode()
-
+// getItems sends a GET request to app-exposer and decodes the list of instant
+// launches in the response.
+func (i *InstantLaunchesAPI) getItems(ctx context.Context, u *url.URL) ([]InstantLaunch, error) {
 	log.Infof("pulling items from %s", u.String())
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
@@ -57,7 +52,7 @@ func (i *InstantLaunchesAPI) PullItems(ctx context.Context) ([]map[string]interf
 		if resp != nil {
 			resp.Body.Close()
 		}
-		return nil, err
+		return nil, newUpstreamRequestError(AppExposerService, u.String(), err)
 	}
 	defer resp.Body.Close()
 
@@ -67,18 +62,29 @@ func (i *InstantLaunchesAPI) PullItems(ctx context.Context) ([]map[string]interf
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("url %s; status code %d; msg %s", u.String(), resp.StatusCode, string(msg))
+		return nil, newUpstreamError(AppExposerService, u.String(), resp.StatusCode, msg)
 	}
 
-	items := make([]map[string]interface{}, 0)
-	if err = json.Unmarshal(msg, &items); err != nil {
-		return nil, err
-	}
+	return parseInstantLaunches(msg)
+}
+
+// PullItems returns the instant launches with the configured metadata
+// attribute and value.
+func (i *InstantLaunchesAPI) PullItems(ctx context.Context) ([]InstantLaunch, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "PullItems")
+	defer span.End()
+
+	fullURL := *i.appExposerURL
+	q := fullURL.Query()
+	q.Set("user", i.appExposerUser)
+	q.Set("attribute", i.attribute)
+	q.Set("value", i.value)
+	fullURL.RawQuery = q.Encode()
 
-	return items, nil
+	return i.getItems(ctx, &fullURL)
 }
 
-func (i *InstantLaunchesAPI) PullItemsAsync(ctx context.Context, itemsChan chan []map[string]interface{}, errChan chan error) {
+func (i *InstantLaunchesAPI) PullItemsAsync(ctx context.Context, itemsChan chan []InstantLaunch, errChan chan error) {
 	items, err := i.PullItems(ctx)
 	if err != nil {
 		errChan <- err
@@ -87,3 +93,39 @@ func (i *InstantLaunchesAPI) PullItemsAsync(ctx context.Context, itemsChan chan
 	errChan <- nil
 	itemsChan <- items
 }
+
+// PullUserItems returns the instant launches added by the user, from the
+// listing app-exposer returns for the user.
+func (i *InstantLaunchesAPI) PullUserItems(ctx context.Context, username string) ([]InstantLaunch, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "PullUserItems")
+	defer span.End()
+
+	fullURL := i.baseURL.JoinPath("instantlaunches", "full")
+	q := fullURL.Query()
+	q.Set("user", fixUsername(username))
+	fullURL.RawQuery = q.Encode()
+
+	items, err := i.getItems(ctx, fullURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Filter(items, func(item InstantLaunch, _ int) bool {
+		return item.AddedByUser(username)
+	}), nil
+}
+
+// PullAllItems returns every instant launch in app-exposer, no matter who added
+// it. The list is meant to be cached and filtered with AddedByUser when
+// PullUserItems fails.
+func (i *InstantLaunchesAPI) PullAllItems(ctx context.Context) ([]InstantLaunch, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "PullAllItems")
+	defer span.End()
+
+	fullURL := i.baseURL.JoinPath("instantlaunches", "full")
+	q := fullURL.Query()
+	q.Set("user", i.appExposerUser)
+	fullURL.RawQuery = q.Encode()
+
+	return i.getItems(ctx, fullURL)
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -12,13 +11,17 @@ import (
 	"go.opentelemetry.io/otel"
 )
 
+// MetadataAPI is a client for the metadata service. It's safe for concurrent
+// use; request URLs are derived from the base URL without modifying it.
 type MetadataAPI struct {
 	metadataURL *url.URL
 }
 
+// NewMetadataAPI returns a new *MetadataAPI. It keeps its own copy of the URL.
 func NewMetadataAPI(metadataURL *url.URL) *MetadataAPI {
+	u := *metadataURL
 	return &MetadataAPI{
-		metadataURL: metadataURL,
+		metadataURL: &u,
 	}
 }
 
@@ -55,7 +58,7 @@ func (m *MetadataAPI) GetFilteredTargetIDs(ctx context.Context, username string,
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, newUpstreamRequestError(MetadataService, fullURL.String(), err)
 	}
 	defer resp.Body.Close()
 
@@ -65,8 +68,7 @@ func (m *MetadataAPI) GetFilteredTargetIDs(ctx context.Context, username string,
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		msg := string(rb)
-		return nil, fmt.Errorf("url %s; status code %d; msg %s", fullURL.String(), resp.StatusCode, msg)
+		return nil, newUpstreamError(MetadataService, fullURL.String(), resp.StatusCode, rb)
 	}
 
 	var data TargetIDs
@@ -78,3 +80,70 @@ func (m *MetadataAPI) GetFilteredTargetIDs(ctx context.Context, username string,
 	retval := data.TargetIDs
 	return retval, nil
 }
+
+// OntologyClass is a class in an ontology hierarchy returned by the metadata
+// service.
+type OntologyClass struct {
+	IRI        string          `json:"iri"`
+	Label      string          `json:"label"`
+	Subclasses []OntologyClass `json:"subclasses"`
+}
+
+type ontologyHierarchies struct {
+	Hierarchies []OntologyClass `json:"hierarchies"`
+}
+
+// FilterHierarchies returns the hierarchies in the version of the ontology
+// that contain the targets, pruned to the branches leading to the classes the
+// targets are tagged with using any of the attributes.
+func (m *MetadataAPI) FilterHierarchies(ctx context.Context, username, ontologyVersion string, attrs, targetTypes, targetIDs []string) ([]OntologyClass, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "FilterHierarchies")
+	defer span.End()
+
+	u := fixUsername(username)
+
+	fullURL := *m.metadataURL.JoinPath("ontologies", ontologyVersion, "filter")
+	q := fullURL.Query()
+	q.Set("user", u)
+	for _, attr := range attrs {
+		q.Add("attr", attr)
+	}
+	fullURL.RawQuery = q.Encode()
+
+	body := map[string]interface{}{}
+	body["target-types"] = targetTypes
+	body["target-ids"] = targetIDs
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullURL.String(), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, newUpstreamRequestError(MetadataService, fullURL.String(), err)
+	}
+	defer resp.Body.Close()
+
+	rb, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newUpstreamError(MetadataService, fullURL.String(), resp.StatusCode, rb)
+	}
+
+	var data ontologyHierarchies
+	if err = json.Unmarshal(rb, &data); err != nil {
+		return nil, err
+	}
+
+	return data.Hierarchies, nil
+}
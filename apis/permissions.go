@@ -3,7 +3,6 @@ package apis
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"io"
 	"net/http"
 	"net/url"
@@ -13,13 +12,19 @@ import (
 	"go.opentelemetry.io/otel"
 )
 
+// PermissionsAPI is a client for the permissions service. It's safe for
+// concurrent use; request URLs are derived from the base URL without modifying
+// it.
 type PermissionsAPI struct {
 	permissionsURL *url.URL
 }
 
+// NewPermissionsAPI returns a new *PermissionsAPI. It keeps its own copy of the
+// URL.
 func NewPermissionsAPI(permissionsURL *url.URL) *PermissionsAPI {
+	u := *permissionsURL
 	return &PermissionsAPI{
-		permissionsURL: permissionsURL,
+		permissionsURL: &u,
 	}
 }
 
@@ -30,6 +35,26 @@ type PermissionsResponse struct {
 	Permissions []Permission `json:"permissions"`
 }
 
+type PermissionResource struct {
+	Name         string `json:"name"`
+	ResourceType string `json:"resource_type"`
+}
+
+type PermissionSubject struct {
+	SubjectID   string `json:"subject_id"`
+	SubjectType string `json:"subject_type"`
+}
+
+type FullPermission struct {
+	PermissionLevel string             `json:"permission_level"`
+	Resource        PermissionResource `json:"resource"`
+	Subject         PermissionSubject  `json:"subject"`
+}
+
+type FullPermissionsResponse struct {
+	Permissions []FullPermission `json:"permissions"`
+}
+
 type group struct {
 	GroupID *string `json:"id"`
 }
@@ -61,17 +86,17 @@ func GetGroupID(ctx context.Context, config *config.ServiceConfiguration) (*stri
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, newUpstreamRequestError(IplantGroupsService, fullURL.String(), err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("status code was not 200")
-	}
 	b, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newUpstreamError(IplantGroupsService, fullURL.String(), resp.StatusCode, b)
+	}
 	var body group
 
 	if err = json.Unmarshal(b, &body); err != nil {
@@ -85,8 +110,7 @@ func (p *PermissionsAPI) GetPublicIDS(ctx context.Context, publicGroupID *string
 	ctx, span := otel.Tracer(otelName).Start(ctx, "GetPublicIDS")
 	defer span.End()
 
-	fullURL := *p.permissionsURL
-	fullURL = *fullURL.JoinPath("permissions", "abbreviated", "subjects", "group", *publicGroupID, "app")
+	fullURL := *p.permissionsURL.JoinPath("permissions", "abbreviated", "subjects", "group", *publicGroupID, "app")
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL.String(), nil)
 	if err != nil {
@@ -95,16 +119,16 @@ func (p *PermissionsAPI) GetPublicIDS(ctx context.Context, publicGroupID *string
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, newUpstreamRequestError(PermissionsService, fullURL.String(), err)
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("status code was not 200")
-	}
 	b, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newUpstreamError(PermissionsService, fullURL.String(), resp.StatusCode, b)
+	}
 	var body PermissionsResponse
 	if err = json.Unmarshal(b, &body); err != nil {
 		return nil, err
@@ -114,3 +138,63 @@ func (p *PermissionsAPI) GetPublicIDS(ctx context.Context, publicGroupID *string
 	})
 	return retval, nil
 }
+
+// GetSharedAppIDs returns the IDs of the apps that have been shared with the
+// user, either directly or through one of their groups. Apps the user owns and
+// permissions granted through the excluded group (normally the public group)
+// are left out.
+func (p *PermissionsAPI) GetSharedAppIDs(ctx context.Context, username string, excludedGroupID *string) ([]string, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "GetSharedAppIDs")
+	defer span.End()
+
+	u := fixUsername(username)
+
+	fullURL := *p.permissionsURL.JoinPath("permissions", "subjects", "user", u, "app")
+	q := fullURL.Query()
+	q.Set("lookup", "true")
+	fullURL.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, newUpstreamRequestError(PermissionsService, fullURL.String(), err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		upstreamErr := newUpstreamError(PermissionsService, fullURL.String(), resp.StatusCode, b)
+		upstreamErr.NamedResource = true
+		return nil, upstreamErr
+	}
+	var body FullPermissionsResponse
+	if err = json.Unmarshal(b, &body); err != nil {
+		return nil, err
+	}
+
+	owned := make(map[string]struct{})
+	for _, item := range body.Permissions {
+		if item.PermissionLevel == "own" {
+			owned[item.Resource.Name] = struct{}{}
+		}
+	}
+	shared := lo.Filter(body.Permissions, func(item FullPermission, index int) bool {
+		if _, ok := owned[item.Resource.Name]; ok {
+			return false
+		}
+		if excludedGroupID != nil && item.Subject.SubjectType == "group" && item.Subject.SubjectID == *excludedGroupID {
+			return false
+		}
+		return true
+	})
+	retval := lo.Uniq(lo.Map(shared, func(item FullPermission, index int) string {
+		return item.Resource.Name
+	}))
+	return retval, nil
+}
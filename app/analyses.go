@@ -3,7 +3,6 @@ package app
 import (
 	"net/http"
 
-	"github.com/cyverse-de/dashboard-aggregator/apis"
 	"github.com/labstack/echo/v4"
 )
 
@@ -11,7 +10,7 @@ func (a *App) RecentAnalysesForUser(c echo.Context) error {
 	ctx := c.Request().Context()
 	log := log.WithField("context", "recent analyses for user")
 
-	username, err := normalizeUsername(c)
+	username, err := a.normalizeUsername(c)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -25,9 +24,7 @@ func (a *App) RecentAnalysesForUser(c echo.Context) error {
 		return err
 	}
 
-	analysisAPI := apis.NewAnalysisAPI(a.appsURL)
-
-	recentAnalyses, err := analysisAPI.RecentAnalyses(ctx, username, int(limit))
+	recentAnalyses, err := a.analysisAPI.RecentAnalyses(ctx, username, int(limit))
 	if err != nil {
 		log.Error(err)
 		return err
@@ -45,7 +42,7 @@ func (a *App) RunningAnalysesForUser(c echo.Context) error {
 	ctx := c.Request().Context()
 	log := log.WithField("context", "recent analyses for user")
 
-	username, err := normalizeUsername(c)
+	username, err := a.normalizeUsername(c)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -59,9 +56,7 @@ func (a *App) RunningAnalysesForUser(c echo.Context) error {
 		return err
 	}
 
-	analysisAPI := apis.NewAnalysisAPI(a.appsURL)
-
-	runningAnalyses, err := analysisAPI.RunningAnalyses(ctx, username, int(limit))
+	runningAnalyses, err := a.analysisAPI.RunningAnalyses(ctx, username, int(limit))
 	if err != nil {
 		log.Error(err)
 		return err
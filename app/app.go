@@ -2,15 +2,18 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/cyverse-de/dashboard-aggregator/apis"
+	"github.com/cyverse-de/dashboard-aggregator/auth"
 	"github.com/cyverse-de/dashboard-aggregator/config"
 	"github.com/cyverse-de/dashboard-aggregator/db"
 	"github.com/cyverse-de/dashboard-aggregator/feeds"
-	"github.com/cyverse-de/go-mod/httperror"
 	"github.com/cyverse-de/go-mod/logging"
 	"github.com/labstack/echo/v4"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
@@ -40,6 +43,34 @@ func normalizeLimit(c echo.Context) (int, error) {
 	return int(limit), nil
 }
 
+func normalizeOffset(c echo.Context) (int, error) {
+	var (
+		offset int64
+		err    error
+	)
+	offsetStr := c.QueryParam("offset")
+	if offsetStr != "" {
+		offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil || offset < 0 {
+			return -1, echo.NewHTTPError(http.StatusBadRequest, "could not parse offset as a non-negative integer")
+		}
+	}
+	return int(offset), nil
+}
+
+func normalizeMinVotes(c echo.Context) (int, error) {
+	minVotes := db.DefaultMinVotes
+	minVotesStr := c.QueryParam("min-votes")
+	if minVotesStr != "" {
+		v, err := strconv.Atoi(minVotesStr)
+		if err != nil || v < 0 {
+			return -1, echo.NewHTTPError(http.StatusBadRequest, "could not parse min-votes as a non-negative integer")
+		}
+		minVotes = v
+	}
+	return minVotes, nil
+}
+
 func normalizeStartDateInterval(c echo.Context) string {
 	startDateInterval := c.QueryParam("start-date-interval")
 	if startDateInterval == "" {
@@ -48,24 +79,116 @@ func normalizeStartDateInterval(c echo.Context) string {
 	return startDateInterval
 }
 
-func normalizeUsername(c echo.Context) (string, error) {
+func normalizePopularitySort(c echo.Context) (db.PopularitySort, error) {
+	sortStr := c.QueryParam("sort")
+	if sortStr == "" {
+		return db.SortByJobs, nil
+	}
+	sortBy, err := db.ParsePopularitySort(sortStr)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return sortBy, nil
+}
+
+// normalizeExpand returns the optional information to include in the apps,
+// from the comma-separated list in the expand query parameter.
+func normalizeExpand(c echo.Context) ([]db.Expansion, error) {
+	var expansions []db.Expansion
+	expandStr := c.QueryParam("expand")
+	if expandStr == "" {
+		return expansions, nil
+	}
+	for _, field := range strings.Split(expandStr, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		e, err := db.ParseExpansion(field)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		expansions = append(expansions, e)
+	}
+	return expansions, nil
+}
+
+// normalizeTrendingInterval returns the length of the windows compared when
+// looking for trending apps.
+func (a *App) normalizeTrendingInterval(c echo.Context) string {
+	trendingInterval := c.QueryParam("trending-interval")
+	if trendingInterval == "" {
+		trendingInterval = a.config.Apps.TrendingInterval
+	}
+	return trendingInterval
+}
+
+// normalizeNewInterval returns the window in which apps must have been
+// integrated or edited to be listed as new.
+func (a *App) normalizeNewInterval(c echo.Context) string {
+	newInterval := c.QueryParam("new-interval")
+	if newInterval == "" {
+		newInterval = a.config.Apps.NewInterval
+	}
+	return newInterval
+}
+
+// anonymousUsername is the username used for requests that aren't made on
+// behalf of a user, such as the logged-out dashboard and the app ID cache
+// refreshes. Pass it through canonicalUsername before using it in database
+// queries, like any other username.
+const anonymousUsername = "anonymous"
+
+// usernameRegexp matches the local part of a valid username.
+var usernameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_.\-]{0,63}$`)
+
+// canonicalUsername validates a username and returns it in its canonical
+// form, which is lowercase and qualified with the configured user domain. The
+// qualified form is used for database lookups; the API clients strip the
+// domain back off for service calls.
+func (a *App) canonicalUsername(username string) (string, error) {
+	domain := strings.ToLower(a.config.Users.Domain)
+
+	name, userDomain, qualified := strings.Cut(strings.ToLower(username), "@")
+	if qualified && userDomain != domain {
+		return "", echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("username must be in the %s domain", domain))
+	}
+	if !usernameRegexp.MatchString(name) {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "username is malformed")
+	}
+
+	return fmt.Sprintf("%s@%s", name, domain), nil
+}
+
+// normalizeUsername returns the canonical form of the username in the request
+// path, falling back to the username of the authenticated caller for routes
+// without one.
+func (a *App) normalizeUsername(c echo.Context) (string, error) {
 	username := c.Param("username")
+	if username == "" {
+		if identity := auth.IdentityFromContext(c); identity != nil {
+			username = identity.Username
+		}
+	}
 	if username == "" {
 		return "", echo.NewHTTPError(http.StatusBadRequest, "username must be in the requested path")
 	}
-	return username, nil
+	return a.canonicalUsername(username)
 }
 
 type App struct {
 	db             *db.Database
 	ec             *echo.Echo
 	pf             *feeds.PublicFeeds
-	ilFeedURL      *url.URL
-	appsURL        *url.URL
-	metadataURL    *url.URL
-	permissionsURL *url.URL
+	ilAPI          *apis.InstantLaunchesAPI
+	analysisAPI    *apis.AnalysisAPI
+	metadataAPI    *apis.MetadataAPI
+	permissionsAPI *apis.PermissionsAPI
 	config         *config.ServiceConfiguration
+	auth           *auth.Authenticator
 	publicGroupID  *string
+	appIDs         appIDCache
+	ils            instantLaunchCache
+	recs           recommendationCache
 }
 
 func (a *App) SetPublicID(ctx context.Context) error {
@@ -79,11 +202,9 @@ func (a *App) SetPublicID(ctx context.Context) error {
 	return nil
 }
 
-func New(db *db.Database, pf *feeds.PublicFeeds, cfg *config.ServiceConfiguration) (*App, error) {
-	ilURL, err := url.Parse(cfg.AppExposer.URL)
-	if err != nil {
-		return nil, err
-	}
+// New returns a new *App. The authenticator may be nil, in which case the
+// user-scoped endpoints are not protected.
+func New(db *db.Database, pf *feeds.PublicFeeds, cfg *config.ServiceConfiguration, authenticator *auth.Authenticator) (*App, error) {
 	appsURL, err := url.Parse(cfg.Apps.URL)
 	if err != nil {
 		return nil, err
@@ -96,43 +217,105 @@ func New(db *db.Database, pf *feeds.PublicFeeds, cfg *config.ServiceConfiguratio
 	if err != nil {
 		return nil, err
 	}
+	ilAPI, err := apis.NewInstantLaunchesAPI(cfg)
+	if err != nil {
+		return nil, err
+	}
 	return &App{
 		db:             db,
 		ec:             echo.New(),
 		pf:             pf,
-		ilFeedURL:      ilURL,
-		appsURL:        appsURL,
-		metadataURL:    metadataURL,
-		permissionsURL: permissionsURL,
+		ilAPI:          ilAPI,
+		analysisAPI:    apis.NewAnalysisAPI(appsURL),
+		metadataAPI:    apis.NewMetadataAPI(metadataURL),
+		permissionsAPI: apis.NewPermissionsAPI(permissionsURL),
 		config:         cfg,
+		auth:           authenticator,
 	}, nil
 }
 
 func (a *App) Echo() *echo.Echo {
 	a.ec.Use(otelecho.Middleware("dashboard-aggregator"))
 
-	a.ec.HTTPErrorHandler = httperror.HTTPErrorHandler
+	a.ec.HTTPErrorHandler = errorHandler
 
 	a.ec.GET("/", a.LoggedOutHandler)
 	a.ec.GET("/healthz", a.HealthzHandler)
 	a.ec.GET("/feeds", a.PublicFeedsHandler)
+	a.ec.GET("/feeds/:name", a.PublicFeedHandler)
 
 	users := a.ec.Group("/users")
-	users.GET("/:username", a.UserDashboardHandler)
-	users.GET("/:username/apps/public", a.PublicAppsForUserHandler)
-	users.GET("/:username/apps/recently-added", a.RecentAddedAppsForUserHandler)
-	users.GET("/:username/apps/popular-featured", a.PopularFeaturedAppsForUserHandler)
-	users.GET("/:username/apps/recently-used", a.RecentlyUsedAppsForUser)
-	users.GET("/:username/analyses/recent", a.RecentAnalysesForUser)
-	users.GET("/:username/analyses/running", a.RunningAnalysesForUser)
+	if a.auth != nil {
+		users.Use(a.auth.Middleware(), a.authorizeUser)
+	}
+	a.userRoutes(users, "/:username")
+
+	// The /me routes get the username from the caller's identity, so they're
+	// only available when authentication is enabled.
+	if a.auth != nil {
+		me := a.ec.Group("/me")
+		me.Use(a.auth.Middleware())
+		a.userRoutes(me, "")
+	}
 
 	apps := a.ec.Group("/apps")
 	apps.GET("/public", a.PublicAppsHandler)
 	apps.GET("/recently-ran", a.RecentlyRunAppsHandler)
+	apps.GET("/popular", a.PopularAppsHandler)
+	apps.GET("/top-rated", a.TopRatedAppsHandler)
+	apps.GET("/trending", a.TrendingAppsHandler)
+	apps.GET("/new", a.NewAppsHandler)
+	apps.GET("/collections/:name", a.CollectionAppsHandler)
+	apps.GET("/search", a.SearchAppsHandler)
 
 	return a.ec
 }
 
+// userRoutes registers the user-scoped endpoints on a group. The prefix is
+// either a path parameter containing the username or empty, in which case the
+// username comes from the authenticated identity.
+func (a *App) userRoutes(g *echo.Group, prefix string) {
+	g.GET(prefix, a.UserDashboardHandler)
+	g.GET(prefix+"/apps/public", a.PublicAppsForUserHandler)
+	g.GET(prefix+"/apps/recently-added", a.RecentAddedAppsForUserHandler)
+	g.GET(prefix+"/apps/popular-featured", a.PopularFeaturedAppsForUserHandler)
+	g.GET(prefix+"/apps/recently-used", a.RecentlyUsedAppsForUser)
+	g.GET(prefix+"/apps/favorites", a.FavoriteAppsForUserHandler)
+	g.GET(prefix+"/apps/shared", a.SharedAppsForUserHandler)
+	g.GET(prefix+"/apps/recommended", a.RecommendedAppsForUserHandler)
+	g.GET(prefix+"/apps/search", a.SearchAppsForUserHandler)
+	g.GET(prefix+"/apps/collections/:name", a.CollectionAppsForUserHandler)
+	g.GET(prefix+"/analyses/recent", a.RecentAnalysesForUser)
+	g.GET(prefix+"/analyses/running", a.RunningAnalysesForUser)
+}
+
+// authorizeUser is middleware that only allows callers to access the
+// endpoints for the user in the request path, unless they have an admin role.
+func (a *App) authorizeUser(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		identity := auth.IdentityFromContext(c)
+		if identity == nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, "authentication is required")
+		}
+
+		requested, err := a.normalizeUsername(c)
+		if err != nil {
+			return err
+		}
+
+		caller, err := a.canonicalUsername(identity.Username)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusForbidden, "the authenticated username is malformed")
+		}
+
+		if caller != requested && !a.auth.IsAdmin(identity) {
+			return echo.NewHTTPError(http.StatusForbidden, "not authorized to access the dashboard for another user")
+		}
+
+		return next(c)
+	}
+}
+
 func (a *App) HealthzHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 	if err := a.db.Healthz(ctx); err != nil {
@@ -147,68 +330,28 @@ func (a *App) PublicFeedsHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, &result)
 }
 
-func (a *App) featuredAppIDs(ctx context.Context, username string, publicAppIDs []string) ([]string, error) {
-	ctx, span := otel.Tracer(otelName).Start(ctx, "featuredAppIDs")
+func (a *App) sharedAppIDs(ctx context.Context, username string) ([]string, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "sharedAppIDs")
 	defer span.End()
 
-	log := log.WithField("context", "featured app ids lookup")
-
-	metadataAPI := apis.NewMetadataAPI(a.metadataURL)
-
-	featuredAppsAVUs := []map[string]string{
-		{
-			"attr":  a.config.Metadata.FeaturedAppsAttribute,
-			"value": a.config.Metadata.FeaturedAppsValue,
-		},
-	}
-
-	log.Debug("getting featured app ids")
-	featuredAppIDs, err := metadataAPI.GetFilteredTargetIDs(ctx, username, []string{"app"}, featuredAppsAVUs, publicAppIDs)
-	if err != nil {
-		return nil, err
-	}
-	log.Debug("done getting featured app ids")
-
-	return featuredAppIDs, nil
-}
-
-func (a *App) featuredAppIDsAsync(ctx context.Context, idsChan chan []string, errChan chan error, username string, publicAppIDs []string) {
-	log.Debug("getting featured app IDs (async)")
-	featuredAppIDs, err := a.featuredAppIDs(ctx, username, publicAppIDs)
-	if err != nil {
-		log.Debug("error getting featured app IDs (async)")
-		errChan <- err
-		return
-	}
-	log.Debug("got featured app IDs (async)")
-	errChan <- nil
-	idsChan <- featuredAppIDs
-}
-
-func (a *App) publicAppIDs(ctx context.Context) ([]string, error) {
-	ctx, span := otel.Tracer(otelName).Start(ctx, "publicAppIDs")
-	defer span.End()
-
-	log := log.WithField("context", "public app ids lookup")
-
-	permissionsAPI := apis.NewPermissionsAPI(a.permissionsURL)
+	log := log.WithField("context", "shared app ids lookup")
 
-	log.Debug("getting public app ids")
-	publicAppIDs, err := permissionsAPI.GetPublicIDS(ctx, a.publicGroupID)
+	log.Debug("getting shared app ids")
+	sharedAppIDs, err := a.permissionsAPI.GetSharedAppIDs(ctx, username, a.publicGroupID)
 	if err != nil {
 		return nil, err
 	}
-	log.Debug("done getting public app ids")
+	log.Debug("done getting shared app ids")
 
-	return publicAppIDs, nil
+	return sharedAppIDs, nil
 }
 
-func (a *App) publicAppIDsAsync(ctx context.Context, idsChan chan []string, errChan chan error) {
-	publicAppIDs, err := a.publicAppIDs(ctx)
+func (a *App) sharedAppIDsAsync(ctx context.Context, idsChan chan []string, errChan chan error, username string) {
+	sharedAppIDs, err := a.sharedAppIDs(ctx, username)
 	if err != nil {
 		errChan <- err
 		return
 	}
 	errChan <- nil
-	idsChan <- publicAppIDs
+	idsChan <- sharedAppIDs
 }
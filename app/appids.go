@@ -0,0 +1,158 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyverse-de/dashboard-aggregator/config"
+	"github.com/robfig/cron/v3"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/sync/singleflight"
+)
+
+// appIDCache holds the public app IDs and the app IDs in each collection
+// between refreshes. The slices are replaced rather than modified, so they may
+// be shared with callers as long as the callers don't modify them.
+type appIDCache struct {
+	mu            sync.RWMutex
+	publicIDs     []string
+	collectionIDs map[string][]string
+	refreshed     time.Time
+
+	// loads makes concurrent requests share a single load when the cache is
+	// empty.
+	loads singleflight.Group
+}
+
+func (c *appIDCache) get() (publicIDs []string, collectionIDs map[string][]string, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.publicIDs, c.collectionIDs, !c.refreshed.IsZero()
+}
+
+func (c *appIDCache) set(publicIDs []string, collectionIDs map[string][]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.publicIDs = publicIDs
+	c.collectionIDs = collectionIDs
+	c.refreshed = time.Now()
+}
+
+// RefreshAppIDs looks up the public app IDs in the permissions service and
+// the app IDs in each collection in the metadata service and caches them. The
+// previously cached IDs are kept if any lookup fails.
+func (a *App) RefreshAppIDs(ctx context.Context) error {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "RefreshAppIDs")
+	defer span.End()
+
+	log := log.WithField("context", "app ids refresh")
+
+	log.Debug("getting public app ids")
+	publicAppIDs, err := a.permissionsAPI.GetPublicIDS(ctx, a.publicGroupID)
+	if err != nil {
+		return err
+	}
+	log.Debug("done getting public app ids")
+
+	collectionIDs := make(map[string][]string)
+	for _, collection := range a.config.Metadata.Collections {
+		avus := make([]map[string]string, 0, len(collection.AVUs))
+		for _, avu := range collection.AVUs {
+			avus = append(avus, map[string]string{
+				"attr":  avu.Attribute,
+				"value": avu.Value,
+			})
+		}
+
+		log.Debugf("getting app ids for collection %s", collection.Name)
+		ids, err := a.metadataAPI.GetFilteredTargetIDs(ctx, anonymousUsername, []string{"app"}, avus, publicAppIDs)
+		if err != nil {
+			return err
+		}
+		log.Debugf("done getting app ids for collection %s", collection.Name)
+
+		collectionIDs[collection.Name] = ids
+	}
+
+	a.appIDs.set(publicAppIDs, collectionIDs)
+
+	return nil
+}
+
+// ScheduleAppIDRefresh refreshes the cached app IDs at the configured
+// interval. Expired recommendations are cleared out at the same time.
+func (a *App) ScheduleAppIDRefresh(ctx context.Context) (*cron.Cron, error) {
+	log := log.WithField("context", "scheduling app id refresh")
+
+	j := cron.New()
+
+	_, err := j.AddFunc(fmt.Sprintf("@every %s", a.config.Apps.IDRefreshInterval), func() {
+		log.Debug("starting refresh of the app ids")
+		if err := a.RefreshAppIDs(ctx); err != nil {
+			log.Error(err)
+		}
+		a.recs.prune()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	j.Start()
+
+	return j, nil
+}
+
+// cachedAppIDs returns the cached public app IDs and collection app IDs,
+// refreshing them first if they haven't been loaded yet. Only one refresh runs
+// at a time; other callers wait for it and share its result.
+func (a *App) cachedAppIDs(ctx context.Context) ([]string, map[string][]string, error) {
+	publicAppIDs, collectionIDs, ok := a.appIDs.get()
+	if ok {
+		return publicAppIDs, collectionIDs, nil
+	}
+
+	// The load is shared, so it isn't canceled along with the request that
+	// happened to start it. Each caller still stops waiting when its own
+	// request is canceled.
+	loaded := a.appIDs.loads.DoChan("app ids", func() (interface{}, error) {
+		return nil, a.RefreshAppIDs(context.WithoutCancel(ctx))
+	})
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case result := <-loaded:
+		if result.Err != nil {
+			return nil, nil, result.Err
+		}
+	}
+
+	publicAppIDs, collectionIDs, _ = a.appIDs.get()
+	return publicAppIDs, collectionIDs, nil
+}
+
+func (a *App) publicAppIDs(ctx context.Context) ([]string, error) {
+	publicAppIDs, _, err := a.cachedAppIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return publicAppIDs, nil
+}
+
+// collectionAppIDs returns the cached IDs of the apps in the named collection
+// that are also in publicAppIDs.
+func (a *App) collectionAppIDs(ctx context.Context, name string, publicAppIDs []string) ([]string, error) {
+	_, collectionIDs, err := a.cachedAppIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Intersect(collectionIDs[name], publicAppIDs), nil
+}
+
+// featuredAppIDs returns the cached featured app IDs that are also in
+// publicAppIDs.
+func (a *App) featuredAppIDs(ctx context.Context, publicAppIDs []string) ([]string, error) {
+	return a.collectionAppIDs(ctx, config.FeaturedCollectionName, publicAppIDs)
+}
@@ -16,6 +16,12 @@ func (a *App) PublicAppsHandler(c echo.Context) error {
 		return err
 	}
 
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
 	publicAppIDs, err := a.publicAppIDs(ctx)
 	if err != nil {
 		log.Error(err)
@@ -23,7 +29,15 @@ func (a *App) PublicAppsHandler(c echo.Context) error {
 	}
 
 	log.Debug("getting public apps")
-	publicApps, err := a.db.PublicAppsQuery(ctx, "", a.config.Apps.FavoritesGroupIndex, publicAppIDs, db.WithQueryLimit(uint(limit)))
+	publicApps, err := a.db.PublicAppsQuery(
+		ctx,
+		"",
+		a.config.Apps.FavoritesGroupIndex,
+		publicAppIDs,
+		db.WithQueryLimit(uint(limit)),
+		a.withExpansions(expand...),
+		db.WithCategory(c.QueryParam("category")),
+	)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -49,6 +63,12 @@ func (a *App) RecentlyRunAppsHandler(c echo.Context) error {
 		return err
 	}
 
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
 	startDateInterval := normalizeStartDateInterval(c)
 
 	publicAppIDs, err := a.publicAppIDs(ctx)
@@ -57,21 +77,211 @@ func (a *App) RecentlyRunAppsHandler(c echo.Context) error {
 		return err
 	}
 
-	log.Debug("getting recently used apps")
-	recentlyUsedApps, err := a.db.RecentlyUsedApps(ctx, &db.AppsQueryConfig{
+	log.Debug("getting recently run apps")
+	recentlyRunApps, err := a.db.RecentlyRunApps(ctx, &db.AppsQueryConfig{
+		AppIDs:            publicAppIDs,
+		StartDateInterval: startDateInterval,
+	}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	log.Debug("done getting recently run apps")
+
+	if err = c.JSON(http.StatusOK, map[string][]db.App{
+		"apps": recentlyRunApps,
+	}); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *App) TopRatedAppsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	limit, err := normalizeLimit(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	minVotes, err := normalizeMinVotes(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	publicAppIDs, err := a.publicAppIDs(ctx)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log.Debug("getting top rated apps")
+	topRatedApps, err := a.db.TopRatedApps(ctx, &db.AppsQueryConfig{
+		Username:    "",
+		GroupsIndex: a.config.Apps.FavoritesGroupIndex,
+		AppIDs:      publicAppIDs,
+	}, minVotes, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	log.Debug("done getting top rated apps")
+
+	if err = c.JSON(http.StatusOK, map[string][]db.App{
+		"apps": topRatedApps,
+	}); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *App) TrendingAppsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	limit, err := normalizeLimit(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	trendingInterval := a.normalizeTrendingInterval(c)
+
+	publicAppIDs, err := a.publicAppIDs(ctx)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log.Debug("getting trending apps")
+	trendingApps, err := a.db.TrendingApps(ctx, &db.AppsQueryConfig{
 		Username:          "",
 		GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
+		AppIDs:            publicAppIDs,
+		StartDateInterval: trendingInterval,
+	}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	log.Debug("done getting trending apps")
+
+	if err = c.JSON(http.StatusOK, map[string][]db.App{
+		"apps": trendingApps,
+	}); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *App) PopularAppsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	limit, err := normalizeLimit(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	startDateInterval := normalizeStartDateInterval(c)
+
+	sortBy, err := normalizePopularitySort(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	publicAppIDs, err := a.publicAppIDs(ctx)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log.Debug("getting popular apps")
+	popularApps, err := a.db.PopularFeaturedApps(ctx, &db.AppsQueryConfig{
 		AppIDs:            publicAppIDs,
 		StartDateInterval: startDateInterval,
-	}, db.WithQueryLimit(uint(limit)))
+		SortBy:            sortBy,
+	}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	log.Debug("done getting popular apps")
+
+	if err = c.JSON(http.StatusOK, map[string][]db.App{
+		"apps": popularApps,
+	}); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *App) NewAppsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	limit, err := normalizeLimit(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	newInterval := a.normalizeNewInterval(c)
+
+	publicAppIDs, err := a.publicAppIDs(ctx)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log.Debug("getting new apps")
+	newApps, err := a.db.NewApps(ctx, &db.AppsQueryConfig{
+		Username:          "",
+		GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
+		AppIDs:            publicAppIDs,
+		StartDateInterval: newInterval,
+	}, a.config.Apps.NewEditThreshold, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
 	if err != nil {
 		log.Error(err)
 		return err
 	}
-	log.Debug("done getting recently used apps")
+	log.Debug("done getting new apps")
 
 	if err = c.JSON(http.StatusOK, map[string][]db.App{
-		"apps": recentlyUsedApps,
+		"apps": newApps,
 	}); err != nil {
 		log.Error(err)
 		return err
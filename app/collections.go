@@ -0,0 +1,205 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/cyverse-de/dashboard-aggregator/db"
+	"github.com/labstack/echo/v4"
+)
+
+// collectionName returns the name of the configured collection in the request
+// path.
+func (a *App) collectionName(c echo.Context) (string, error) {
+	name := c.Param("name")
+	if _, ok := a.config.Metadata.Collection(name); !ok {
+		return "", echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("collection %s does not exist", name))
+	}
+	return name, nil
+}
+
+// dashboardCollections returns the apps in each collection that's configured
+// to be shown on the dashboards, keyed by collection name. The cfg.AppIDs field
+// contains the public app IDs.
+func (a *App) dashboardCollections(ctx context.Context, cfg *db.AppsQueryConfig, opts ...db.QueryOption) (map[string][]db.App, error) {
+	collections := make(map[string][]db.App)
+
+	for _, collection := range a.config.Metadata.Collections {
+		if !collection.Dashboard {
+			continue
+		}
+
+		appIDs, err := a.collectionAppIDs(ctx, collection.Name, cfg.AppIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		apps, err := a.db.PopularFeaturedApps(ctx, &db.AppsQueryConfig{
+			Username:          cfg.Username,
+			GroupsIndex:       cfg.GroupsIndex,
+			AppIDs:            appIDs,
+			StartDateInterval: cfg.StartDateInterval,
+			SortBy:            cfg.SortBy,
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		collections[collection.Name] = apps
+	}
+
+	return collections, nil
+}
+
+func (a *App) dashboardCollectionsAsync(ctx context.Context, collectionsChan chan map[string][]db.App, errChan chan error, cfg *db.AppsQueryConfig, opts ...db.QueryOption) {
+	collections, err := a.dashboardCollections(ctx, cfg, opts...)
+	if err != nil {
+		errChan <- err
+		return
+	}
+	errChan <- nil
+	collectionsChan <- collections
+}
+
+func (a *App) CollectionAppsHandler(c echo.Context) error {
+	log := log.WithField("context", "collection apps")
+
+	ctx := c.Request().Context()
+
+	name, err := a.collectionName(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log = log.WithField("collection", name)
+
+	limit, err := normalizeLimit(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	startDateInterval := normalizeStartDateInterval(c)
+
+	sortBy, err := normalizePopularitySort(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	publicAppIDs, err := a.publicAppIDs(ctx)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	collectionAppIDs, err := a.collectionAppIDs(ctx, name, publicAppIDs)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log.Debug("getting collection apps")
+	apps, err := a.db.PopularFeaturedApps(ctx, &db.AppsQueryConfig{
+		Username:          "",
+		GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
+		AppIDs:            collectionAppIDs,
+		StartDateInterval: startDateInterval,
+		SortBy:            sortBy,
+	}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	log.Debug("done getting collection apps")
+
+	if err = c.JSON(http.StatusOK, map[string][]db.App{
+		"apps": apps,
+	}); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *App) CollectionAppsForUserHandler(c echo.Context) error {
+	log := log.WithField("context", "collection apps for user")
+
+	ctx := c.Request().Context()
+
+	username, err := a.normalizeUsername(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	name, err := a.collectionName(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log = log.WithField("user", username).WithField("collection", name)
+
+	limit, err := normalizeLimit(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	startDateInterval := normalizeStartDateInterval(c)
+
+	sortBy, err := normalizePopularitySort(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	publicAppIDs, err := a.publicAppIDs(ctx)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	collectionAppIDs, err := a.collectionAppIDs(ctx, name, publicAppIDs)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	apps, err := a.db.PopularFeaturedApps(ctx, &db.AppsQueryConfig{
+		Username:          username,
+		GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
+		AppIDs:            collectionAppIDs,
+		StartDateInterval: startDateInterval,
+		SortBy:            sortBy,
+	}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	if err = c.JSON(http.StatusOK, map[string][]db.App{
+		"apps": apps,
+	}); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/cyverse-de/dashboard-aggregator/apis"
+	"github.com/cyverse-de/go-mod/httperror"
+	"github.com/labstack/echo/v4"
+)
+
+// errorHandler converts errors from upstream services into error responses
+// with a meaningful status code before handing them off to
+// httperror.HTTPErrorHandler. The full upstream error, which may include
+// internal URLs and response bodies, is only logged.
+func errorHandler(err error, c echo.Context) {
+	var upstreamErr *apis.UpstreamError
+	if errors.As(err, &upstreamErr) {
+		log.WithField("context", "error handler").Error(upstreamErr)
+		status := upstreamErr.HTTPStatus()
+		details := upstreamErr.Details()
+		err = httperror.ErrorResponse{
+			Message:        upstreamErr.ClientMessage(),
+			ErrorCode:      status,
+			HTTPStatusCode: status,
+			Details:        &details,
+		}
+	}
+	httperror.HTTPErrorHandler(err, c)
+}
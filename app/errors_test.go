@@ -0,0 +1,76 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cyverse-de/dashboard-aggregator/apis"
+	"github.com/cyverse-de/dashboard-aggregator/config"
+	"github.com/labstack/echo/v4"
+)
+
+const upstreamBody = "internal stack trace"
+
+// newErrorTestApp returns an App whose apps service always responds with the
+// given status code.
+func newErrorTestApp(t *testing.T, status int) (*App, *httptest.Server) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, upstreamBody, status)
+	}))
+	t.Cleanup(srv.Close)
+
+	appsURL, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &App{
+		ec:          echo.New(),
+		analysisAPI: apis.NewAnalysisAPI(appsURL),
+		config: &config.ServiceConfiguration{
+			Users: &config.UsersConfiguration{Domain: "iplantcollaborative.org"},
+		},
+	}
+	a.ec.HTTPErrorHandler = errorHandler
+	a.userRoutes(a.ec.Group("/users"), "/:username")
+
+	return a, srv
+}
+
+func TestErrorHandlerStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		upstream int
+		expected int
+	}{
+		{"unknown user", http.StatusNotFound, http.StatusNotFound},
+		{"bad request", http.StatusBadRequest, http.StatusBadGateway},
+		{"server error", http.StatusInternalServerError, http.StatusBadGateway},
+		{"gateway timeout", http.StatusGatewayTimeout, http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		for _, path := range []string{"/users/nobody/analyses/recent", "/users/nobody/analyses/running"} {
+			t.Run(tt.name+" "+path, func(t *testing.T) {
+				a, srv := newErrorTestApp(t, tt.upstream)
+
+				rec := httptest.NewRecorder()
+				a.ec.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+				if rec.Code != tt.expected {
+					t.Fatalf("expected status %d, got %d: %s", tt.expected, rec.Code, rec.Body.String())
+				}
+				for _, leaked := range []string{srv.URL, upstreamBody} {
+					if strings.Contains(rec.Body.String(), leaked) {
+						t.Errorf("response contains %q: %s", leaked, rec.Body.String())
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyverse-de/dashboard-aggregator/feeds"
+	"github.com/labstack/echo/v4"
+)
+
+// maxFeedQueryLength is the longest feed search query that's accepted.
+const maxFeedQueryLength = 256
+
+// normalizeFeedFilter returns the filter described by the limit, since, and q
+// query parameters. Unlike the app listings, feeds aren't limited unless the
+// limit parameter is present. The since parameter may be an RFC 3339 timestamp
+// or a date.
+func normalizeFeedFilter(c echo.Context) (*feeds.ItemFilter, error) {
+	filter := &feeds.ItemFilter{}
+
+	limitStr := c.QueryParam("limit")
+	if limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "could not parse limit as a positive integer")
+		}
+		filter.Limit = limit
+	}
+
+	sinceStr := c.QueryParam("since")
+	if sinceStr != "" {
+		since, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			since, err = time.Parse(time.DateOnly, sinceStr)
+		}
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "could not parse since as a date or an RFC 3339 timestamp")
+		}
+		filter.Since = since
+	}
+
+	filter.Query = strings.TrimSpace(c.QueryParam("q"))
+	if len(filter.Query) > maxFeedQueryLength {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "the q query parameter is too long")
+	}
+
+	return filter, nil
+}
+
+func (a *App) PublicFeedHandler(c echo.Context) error {
+	log := log.WithField("context", "public feed")
+
+	ctx := c.Request().Context()
+
+	name := c.Param("name")
+	log = log.WithField("feed", name)
+
+	filter, err := normalizeFeedFilter(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	items, ok := a.pf.Items(ctx, name)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("feed %s does not exist", name))
+	}
+
+	if err = c.JSON(http.StatusOK, map[string][]feeds.DashboardItem{
+		"items": filter.Apply(items),
+	}); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyverse-de/dashboard-aggregator/feeds"
+	"github.com/labstack/echo/v4"
+)
+
+func TestNormalizeFeedFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected *feeds.ItemFilter
+		status   int
+	}{
+		{"no parameters", "", &feeds.ItemFilter{}, 0},
+		{"limit", "limit=5", &feeds.ItemFilter{Limit: 5}, 0},
+		{"zero limit", "limit=0", nil, http.StatusBadRequest},
+		{"negative limit", "limit=-1", nil, http.StatusBadRequest},
+		{"non-numeric limit", "limit=ten", nil, http.StatusBadRequest},
+		{"RFC 3339 since", "since=2024-03-01T12:00:00Z", &feeds.ItemFilter{Since: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}, 0},
+		{"bare date since", "since=2024-03-01", &feeds.ItemFilter{Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}, 0},
+		{"unparseable since", "since=yesterday", nil, http.StatusBadRequest},
+		{"query", "q=+Genomics+", &feeds.ItemFilter{Query: "Genomics"}, 0},
+		{"query too long", "q=" + strings.Repeat("a", maxFeedQueryLength+1), nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/feeds/news?"+tt.query, nil)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			filter, err := normalizeFeedFilter(c)
+			if tt.status != 0 {
+				httpErr, ok := err.(*echo.HTTPError)
+				if !ok {
+					t.Fatalf("expected an *echo.HTTPError, got %v", err)
+				}
+				if httpErr.Code != tt.status {
+					t.Errorf("expected status %d, got %d", tt.status, httpErr.Code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(filter, tt.expected) {
+				t.Errorf("expected filter %+v, got %+v", tt.expected, filter)
+			}
+		})
+	}
+}
+
+func TestPublicFeedHandler(t *testing.T) {
+	feed := feeds.NewWebsiteFeed("https://example.org/feed", 10)
+	feed.SetItems([]feeds.DashboardItem{
+		{ID: "1", Name: "Genomics Workshop", DateAdded: "2024-03-01T12:00:00Z"},
+		{ID: "2", Name: "Maintenance", DateAdded: "2024-02-01T12:00:00Z"},
+	})
+	pf := feeds.NewPublicFeeds()
+	pf.AddFeed(context.Background(), "news", feed)
+
+	a := &App{ec: echo.New(), pf: pf}
+	a.ec.HTTPErrorHandler = errorHandler
+	a.ec.GET("/feeds/:name", a.PublicFeedHandler)
+
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		expected []string
+	}{
+		{"all items", "/feeds/news", http.StatusOK, []string{"1", "2"}},
+		{"filtered", "/feeds/news?since=2024-02-15&q=GENOMICS", http.StatusOK, []string{"1"}},
+		{"unknown feed", "/feeds/unknown", http.StatusNotFound, nil},
+		{"bad since", "/feeds/news?since=yesterday", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			a.ec.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var body map[string][]feeds.DashboardItem
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, 0, len(body["items"]))
+			for _, item := range body["items"] {
+				ids = append(ids, item.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("expected items %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cyverse-de/dashboard-aggregator/apis"
+	"github.com/cyverse-de/dashboard-aggregator/db"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxHierarchyLookups is the number of requests for app hierarchies that may
+// be sent to the metadata service at once.
+const maxHierarchyLookups = 8
+
+// hierarchyClasses converts the classes returned by the metadata service into
+// the form included in app listings.
+func hierarchyClasses(classes []apis.OntologyClass) []db.HierarchyClass {
+	if len(classes) == 0 {
+		return nil
+	}
+	return lo.Map(classes, func(c apis.OntologyClass, _ int) db.HierarchyClass {
+		return db.HierarchyClass{
+			IRI:        c.IRI,
+			Label:      c.Label,
+			Subclasses: hierarchyClasses(c.Subclasses),
+		}
+	})
+}
+
+// appHierarchies looks up the ontology hierarchies each app is classified
+// under in the metadata service. The hierarchies are optional, so if there's
+// no ontology version in use or the lookup fails, the apps are listed without
+// them.
+func (a *App) appHierarchies(ctx context.Context, username string, appIDs []string) (map[string][]db.HierarchyClass, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "appHierarchies")
+	defer span.End()
+
+	log := log.WithField("context", "app hierarchies")
+
+	version, err := a.db.HierarchyVersion(ctx)
+	if err != nil {
+		log.Errorf("unable to look up the app hierarchy version, leaving hierarchies out: %s", err)
+		return nil, nil
+	}
+	if version == "" {
+		return nil, nil
+	}
+
+	if username == "" {
+		username = anonymousUsername
+	}
+
+	// The metadata service merges the hierarchies of all of the targets in a
+	// request, so each app is looked up separately.
+	var mu sync.Mutex
+	hierarchies := make(map[string][]db.HierarchyClass)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxHierarchyLookups)
+	for _, appID := range lo.Uniq(appIDs) {
+		appID := appID
+		g.Go(func() error {
+			classes, err := a.metadataAPI.FilterHierarchies(gctx, username, version, a.config.Metadata.HierarchyAttributes, []string{"app"}, []string{appID})
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			hierarchies[appID] = hierarchyClasses(classes)
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		log.Errorf("unable to look up app hierarchies, leaving them out: %s", err)
+		return nil, nil
+	}
+
+	return hierarchies, nil
+}
+
+// withExpansions returns a query option that includes the optional
+// information in the apps, including the hierarchies, which are looked up in
+// the metadata service.
+func (a *App) withExpansions(expansions ...db.Expansion) db.QueryOption {
+	return func(s *db.QuerySettings) {
+		db.WithExpansions(expansions...)(s)
+		db.WithHierarchyLookup(a.appHierarchies)(s)
+	}
+}
@@ -0,0 +1,242 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cyverse-de/dashboard-aggregator/apis"
+	"github.com/cyverse-de/dashboard-aggregator/db"
+	"github.com/robfig/cron/v3"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel"
+)
+
+// instantLaunchCache holds the instant launches selected by metadata between
+// refreshes, along with the full list of instant launches when per-user
+// instant launches are enabled. The full list is only used when a user's own
+// instant launches can't be pulled from app-exposer. The slices are replaced
+// rather than modified, so they may be shared with callers as long as the
+// callers don't modify them.
+type instantLaunchCache struct {
+	mu        sync.RWMutex
+	items     []apis.InstantLaunch
+	refreshed time.Time
+	all       []apis.InstantLaunch
+}
+
+func (c *instantLaunchCache) get() ([]apis.InstantLaunch, time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.items, c.refreshed
+}
+
+func (c *instantLaunchCache) set(items []apis.InstantLaunch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = items
+	c.refreshed = time.Now()
+}
+
+func (c *instantLaunchCache) getAll() []apis.InstantLaunch {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.all
+}
+
+func (c *instantLaunchCache) setAll(all []apis.InstantLaunch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.all = all
+}
+
+// InstantLaunchCacheStatus describes how fresh the cached instant launches
+// are. The fields are nil if the instant launches have never been loaded.
+type InstantLaunchCacheStatus struct {
+	RefreshedAt *time.Time `json:"refreshed_at"`
+	AgeSeconds  *int64     `json:"age_seconds"`
+}
+
+func (a *App) instantLaunchCacheStatus() *InstantLaunchCacheStatus {
+	_, refreshed := a.ils.get()
+	if refreshed.IsZero() {
+		return &InstantLaunchCacheStatus{}
+	}
+	age := int64(time.Since(refreshed).Seconds())
+	return &InstantLaunchCacheStatus{
+		RefreshedAt: &refreshed,
+		AgeSeconds:  &age,
+	}
+}
+
+// RefreshInstantLaunches pulls the instant launches selected by metadata from
+// app-exposer and caches them, along with the full list of instant launches if
+// per-user instant launches are enabled. The previously cached instant
+// launches are kept if a pull fails.
+func (a *App) RefreshInstantLaunches(ctx context.Context) error {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "RefreshInstantLaunches")
+	defer span.End()
+
+	items, err := a.ilAPI.PullItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.ils.set(items)
+
+	if a.config.AppExposer.UserInstantLaunches {
+		all, err := a.ilAPI.PullAllItems(ctx)
+		if err != nil {
+			return err
+		}
+
+		a.ils.setAll(all)
+	}
+
+	return nil
+}
+
+// ScheduleInstantLaunchRefresh refreshes the cached instant launches at the
+// configured interval.
+func (a *App) ScheduleInstantLaunchRefresh(ctx context.Context) (*cron.Cron, error) {
+	log := log.WithField("context", "scheduling instant launch refresh")
+
+	j := cron.New()
+
+	_, err := j.AddFunc(fmt.Sprintf("@every %s", a.config.AppExposer.InstantLaunchRefreshInterval), func() {
+		log.Debug("starting refresh of the instant launches")
+		if err := a.RefreshInstantLaunches(ctx); err != nil {
+			log.Error(err)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	j.Start()
+
+	return j, nil
+}
+
+// dashboardInstantLaunches returns the cached instant launches, followed by
+// the user's own instant launches if those are enabled, with the app details
+// and launch links filled in. The user's instant launches are pulled from
+// app-exposer on each request. If that fails, they're taken from the cached
+// full list instead, which may be up to one refresh interval out of date.
+func (a *App) dashboardInstantLaunches(ctx context.Context, username string) ([]apis.InstantLaunch, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "dashboardInstantLaunches")
+	defer span.End()
+
+	log := log.WithField("context", "dashboard instant launches")
+
+	items, _ := a.ils.get()
+
+	if a.config.AppExposer.UserInstantLaunches {
+		userItems, err := a.ilAPI.PullUserItems(ctx, username)
+		if err != nil {
+			log.Errorf("unable to get the user's instant launches, using the cached list: %s", err)
+			userItems = lo.Filter(a.ils.getAll(), func(item apis.InstantLaunch, _ int) bool {
+				return item.AddedByUser(username)
+			})
+		}
+		items = lo.UniqBy(append(append([]apis.InstantLaunch{}, items...), userItems...), func(item apis.InstantLaunch) string {
+			return item.ID
+		})
+	}
+
+	return a.enrichInstantLaunches(ctx, username, items, false)
+}
+
+// loggedOutInstantLaunches returns the cached instant launches that start
+// public quick launches of public apps, with links that go through the login
+// page. It returns an empty list if they're turned off in the configuration.
+func (a *App) loggedOutInstantLaunches(ctx context.Context, publicAppIDs []string) ([]apis.InstantLaunch, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "loggedOutInstantLaunches")
+	defer span.End()
+
+	if !a.config.AppExposer.LoggedOutInstantLaunches {
+		return []apis.InstantLaunch{}, nil
+	}
+
+	items, _ := a.ils.get()
+
+	items = lo.Filter(items, func(item apis.InstantLaunch, _ int) bool {
+		return item.QuickLaunch.IsPublic && lo.Contains(publicAppIDs, item.QuickLaunch.AppID)
+	})
+
+	return a.enrichInstantLaunches(ctx, "", items, true)
+}
+
+// instantLaunchURL returns the link that starts the instant launch, or an
+// empty string if links aren't configured. If viaLogin is true, the link goes
+// through the login page first.
+func (a *App) instantLaunchURL(item *apis.InstantLaunch, viaLogin bool) string {
+	launchURL := a.config.AppExposer.InstantLaunchURL
+	if launchURL == "" {
+		return ""
+	}
+	launchURL = strings.ReplaceAll(launchURL, "{id}", url.PathEscape(item.ID))
+
+	if !viaLogin || a.config.AppExposer.InstantLaunchLoginURL == "" {
+		return launchURL
+	}
+	return strings.ReplaceAll(a.config.AppExposer.InstantLaunchLoginURL, "{redirect}", url.QueryEscape(launchURL))
+}
+
+func (a *App) dashboardInstantLaunchesAsync(ctx context.Context, itemsChan chan []apis.InstantLaunch, errChan chan error, username string) {
+	items, err := a.dashboardInstantLaunches(ctx, username)
+	if err != nil {
+		errChan <- err
+		return
+	}
+	errChan <- nil
+	itemsChan <- items
+}
+
+// enrichInstantLaunches fills in the name, description, and favorite status of
+// the app started by each instant launch, along with the launch link. The
+// username may be empty, in which case none of the apps are favorites. The
+// viaLogin flag is passed along to instantLaunchURL.
+func (a *App) enrichInstantLaunches(ctx context.Context, username string, items []apis.InstantLaunch, viaLogin bool) ([]apis.InstantLaunch, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "enrichInstantLaunches")
+	defer span.End()
+
+	if len(items) == 0 {
+		return []apis.InstantLaunch{}, nil
+	}
+
+	appIDs := lo.Uniq(lo.Map(items, func(item apis.InstantLaunch, _ int) string {
+		return item.QuickLaunch.AppID
+	}))
+
+	apps, err := a.db.AppsByID(ctx, &db.AppsQueryConfig{
+		Username:    username,
+		GroupsIndex: a.config.Apps.FavoritesGroupIndex,
+	}, appIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	appsByID := lo.KeyBy(apps, func(app db.App) string {
+		return app.ID
+	})
+
+	enriched := make([]apis.InstantLaunch, len(items))
+	for i, item := range items {
+		if app, ok := appsByID[item.QuickLaunch.AppID]; ok {
+			item.App = &apis.InstantLaunchApp{
+				ID:          app.ID,
+				Name:        app.Name,
+				Description: app.Description.String,
+				IsFavorite:  app.IsFavorite,
+			}
+		}
+		item.LaunchURL = a.instantLaunchURL(&item, viaLogin)
+		enriched[i] = item
+	}
+
+	return enriched, nil
+}
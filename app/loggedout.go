@@ -3,6 +3,7 @@ package app
 import (
 	"net/http"
 
+	"github.com/cyverse-de/dashboard-aggregator/apis"
 	"github.com/cyverse-de/dashboard-aggregator/db"
 	"github.com/labstack/echo/v4"
 )
@@ -16,10 +17,26 @@ func (a *App) LoggedOutHandler(c echo.Context) error {
 		return err
 	}
 
-	username := "anonymous"
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	username, err := a.canonicalUsername(anonymousUsername)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
 
 	startDateInterval := normalizeStartDateInterval(c)
 
+	sortBy, err := normalizePopularitySort(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
 	feeds := a.pf.Marshallable(ctx)
 
 	publicAppIDs, err := a.publicAppIDs(ctx)
@@ -28,7 +45,7 @@ func (a *App) LoggedOutHandler(c echo.Context) error {
 		return err
 	}
 
-	featuredAppIDs, err := a.featuredAppIDs(ctx, username, publicAppIDs)
+	featuredAppIDs, err := a.featuredAppIDs(ctx, publicAppIDs)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -39,17 +56,41 @@ func (a *App) LoggedOutHandler(c echo.Context) error {
 		GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
 		AppIDs:            featuredAppIDs,
 		StartDateInterval: startDateInterval,
-	}, db.WithQueryLimit(uint(limit)))
+		SortBy:            sortBy,
+	}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	collections, err := a.dashboardCollections(ctx, &db.AppsQueryConfig{
+		Username:          username,
+		GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
+		AppIDs:            publicAppIDs,
+		StartDateInterval: startDateInterval,
+		SortBy:            sortBy,
+	}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
 	if err != nil {
 		log.Error(err)
 		return err
 	}
 
+	// Instant launches are an optional part of the dashboard, so they're left
+	// out rather than failing the whole request.
+	ilItems, err := a.loggedOutInstantLaunches(ctx, publicAppIDs)
+	if err != nil {
+		log.Errorf("unable to get instant launches, leaving the section empty: %s", err)
+		ilItems = []apis.InstantLaunch{}
+	}
+
 	if err = c.JSON(http.StatusOK, map[string]interface{}{
-		"apps": map[string][]db.App{
+		"apps": map[string]interface{}{
 			"popularFeatured": popularFeaturedApps,
+			"collections":     collections,
 		},
-		"feeds": feeds,
+		"feeds":                feeds,
+		"instantLaunches":      ilItems,
+		"instantLaunchesCache": a.instantLaunchCacheStatus(),
 	}); err != nil {
 		log.Error(err)
 		return err
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cyverse-de/dashboard-aggregator/db"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/sync/singleflight"
+)
+
+// maxRecommendations is the number of recommendations cached for each user,
+// which limits how far the recommended apps can be paged through.
+const maxRecommendations = 100
+
+type recommendationCacheEntry struct {
+	recommendations []db.Recommendation
+	expires         time.Time
+}
+
+// recommendationCache holds the recommendations for each user until they
+// expire. Recommendations are expensive to compute and change slowly, so they
+// don't need to be recomputed on every dashboard load.
+type recommendationCache struct {
+	mu      sync.Mutex
+	entries map[string]recommendationCacheEntry
+
+	// loads makes concurrent requests for the same user share a single
+	// load, keyed by username.
+	loads singleflight.Group
+}
+
+func (c *recommendationCache) get(username string) ([]db.Recommendation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[username]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.recommendations, true
+}
+
+func (c *recommendationCache) set(username string, recommendations []db.Recommendation, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]recommendationCacheEntry)
+	}
+	c.entries[username] = recommendationCacheEntry{
+		recommendations: recommendations,
+		expires:         time.Now().Add(ttl),
+	}
+}
+
+// prune removes the expired entries from the cache.
+func (c *recommendationCache) prune() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for username, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, username)
+		}
+	}
+}
+
+// recommendations returns the cached recommendations for the user in
+// cfg.Username, computing them first if they aren't cached or have expired.
+// Concurrent cache misses for the same user share one computation.
+func (a *App) recommendations(ctx context.Context, cfg *db.AppsQueryConfig) ([]db.Recommendation, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "recommendations")
+	defer span.End()
+
+	if recommendations, ok := a.recs.get(cfg.Username); ok {
+		return recommendations, nil
+	}
+
+	// The load is shared, so it isn't canceled along with the request that
+	// happened to start it. Each caller still stops waiting when its own
+	// request is canceled.
+	loaded := a.recs.loads.DoChan(cfg.Username, func() (interface{}, error) {
+		recommendations, err := a.db.Recommendations(context.WithoutCancel(ctx), cfg, maxRecommendations)
+		if err != nil {
+			return nil, err
+		}
+		a.recs.set(cfg.Username, recommendations, a.config.Apps.RecommendedCacheTTL)
+		return recommendations, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-loaded:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.([]db.Recommendation), nil
+	}
+}
+
+// recommendedApps returns the apps recommended to the user in cfg.Username.
+func (a *App) recommendedApps(ctx context.Context, cfg *db.AppsQueryConfig, opts ...db.QueryOption) ([]db.App, error) {
+	recommendations, err := a.recommendations(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return a.db.RecommendedApps(ctx, cfg, recommendations, opts...)
+}
+
+func (a *App) recommendedAppsAsync(ctx context.Context, appsChan chan []db.App, errChan chan error, cfg *db.AppsQueryConfig, opts ...db.QueryOption) {
+	apps, err := a.recommendedApps(ctx, cfg, opts...)
+	if err != nil {
+		errChan <- err
+		return
+	}
+	errChan <- nil
+	appsChan <- apps
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/cyverse-de/dashboard-aggregator/db"
+	"github.com/labstack/echo/v4"
+)
+
+// maxSearchLength is the longest search query that's accepted.
+const maxSearchLength = 256
+
+// normalizeSearch returns the search described by the q and include query
+// parameters. The include parameter is a comma-separated list containing
+// tools and/or categories.
+func normalizeSearch(c echo.Context) (*db.AppSearch, error) {
+	query := strings.TrimSpace(c.QueryParam("q"))
+	if query == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "the q query parameter is required")
+	}
+	if len(query) > maxSearchLength {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "the q query parameter is too long")
+	}
+
+	search := &db.AppSearch{Query: query}
+
+	include := c.QueryParam("include")
+	if include != "" {
+		for _, field := range strings.Split(include, ",") {
+			switch strings.TrimSpace(field) {
+			case "tools":
+				search.IncludeTools = true
+			case "categories":
+				search.IncludeCategories = true
+			case "":
+			default:
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "include must contain only tools and categories")
+			}
+		}
+	}
+
+	return search, nil
+}
+
+func (a *App) SearchAppsHandler(c echo.Context) error {
+	log := log.WithField("context", "search apps")
+
+	ctx := c.Request().Context()
+
+	limit, err := normalizeLimit(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	offset, err := normalizeOffset(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	search, err := normalizeSearch(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	publicAppIDs, err := a.publicAppIDs(ctx)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log.Debug("searching apps")
+	apps, err := a.db.SearchApps(ctx, &db.AppsQueryConfig{
+		Username:    "",
+		GroupsIndex: a.config.Apps.FavoritesGroupIndex,
+		AppIDs:      publicAppIDs,
+	}, search, db.WithQueryLimit(uint(limit)), db.WithQueryOffset(uint(offset)), a.withExpansions(expand...))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	log.Debug("done searching apps")
+
+	if err = c.JSON(http.StatusOK, map[string][]db.App{
+		"apps": apps,
+	}); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *App) SearchAppsForUserHandler(c echo.Context) error {
+	log := log.WithField("context", "search apps for user")
+
+	ctx := c.Request().Context()
+
+	username, err := a.normalizeUsername(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log = log.WithField("user", username)
+
+	limit, err := normalizeLimit(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	offset, err := normalizeOffset(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	search, err := normalizeSearch(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	publicAppIDs, err := a.publicAppIDs(ctx)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	search.SharedAppIDs, err = a.sharedAppIDs(ctx, username)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log.Debug("searching apps")
+	apps, err := a.db.SearchApps(ctx, &db.AppsQueryConfig{
+		Username:    username,
+		GroupsIndex: a.config.Apps.FavoritesGroupIndex,
+		AppIDs:      publicAppIDs,
+	}, search, db.WithQueryLimit(uint(limit)), db.WithQueryOffset(uint(offset)), a.withExpansions(expand...))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	log.Debug("done searching apps")
+
+	if err = c.JSON(http.StatusOK, map[string][]db.App{
+		"apps": apps,
+	}); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
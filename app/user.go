@@ -15,7 +15,7 @@ func (a *App) UserDashboardHandler(c echo.Context) error {
 
 	ctx := c.Request().Context()
 
-	username, err := normalizeUsername(c)
+	username, err := a.normalizeUsername(c)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -29,98 +29,170 @@ func (a *App) UserDashboardHandler(c echo.Context) error {
 		return err
 	}
 
-	// Fetch instant launches
-	ilAPI, err := apis.NewInstantLaunchesAPI(a.config)
+	expand, err := normalizeExpand(c)
 	if err != nil {
 		log.Error(err)
 		return err
 	}
 
-	ilChan := make(chan []map[string]interface{})
-	ilErrChan := make(chan error)
+	// The channels are buffered so that the goroutines can finish even if the
+	// handler returns early because of an error.
+
+	// Fetch instant launches
+	ilChan := make(chan []apis.InstantLaunch, 1)
+	ilErrChan := make(chan error, 1)
 
-	go ilAPI.PullItemsAsync(ctx, ilChan, ilErrChan)
+	go a.dashboardInstantLaunchesAsync(ctx, ilChan, ilErrChan, username)
 
 	// Fetch recent & running analyses
-	analysisAPI := apis.NewAnalysisAPI(a.appsURL)
+	recentAnalysisChan := make(chan *apis.AnalysisListing, 1)
+	recentAnalysisErrChan := make(chan error, 1)
 
-	recentAnalysisChan := make(chan *apis.AnalysisListing)
-	recentAnalysisErrChan := make(chan error)
+	runningAnalysisChan := make(chan *apis.AnalysisListing, 1)
+	runningAnalysisErrChan := make(chan error, 1)
 
-	runningAnalysisChan := make(chan *apis.AnalysisListing)
-	runningAnalysisErrChan := make(chan error)
+	go a.analysisAPI.RecentAnalysesAsync(ctx, recentAnalysisChan, recentAnalysisErrChan, username, int(limit))
+	go a.analysisAPI.RunningAnalysesAsync(ctx, runningAnalysisChan, runningAnalysisErrChan, username, int(limit))
 
-	go analysisAPI.RecentAnalysesAsync(ctx, recentAnalysisChan, recentAnalysisErrChan, username, int(limit))
-	go analysisAPI.RunningAnalysesAsync(ctx, runningAnalysisChan, runningAnalysisErrChan, username, int(limit))
+	sharedAppIDsChan := make(chan []string, 1)
+	sharedAppIDsErrChan := make(chan error, 1)
 
-	// Fetch public & featured app IDs
-	publicAppIDsChan := make(chan []string)
-	publicAppIDsErrChan := make(chan error)
+	go a.sharedAppIDsAsync(ctx, sharedAppIDsChan, sharedAppIDsErrChan, username)
 
-	go a.publicAppIDsAsync(ctx, publicAppIDsChan, publicAppIDsErrChan)
+	// The public and featured app IDs are cached, so these don't block.
+	publicAppIDs, err := a.publicAppIDs(ctx)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
 
-	// We need public app IDs for the next few calls
-	err = <-publicAppIDsErrChan
+	featuredAppIDs, err := a.featuredAppIDs(ctx, publicAppIDs)
 	if err != nil {
 		log.Error(err)
 		return err
 	}
-	publicAppIDs := <-publicAppIDsChan
 
-	featuredAppIDsChan := make(chan []string)
-	featuredAppIDsErrChan := make(chan error)
+	recentlyAddedAppsChan := make(chan []db.App, 1)
+	recentlyAddedAppsErrChan := make(chan error, 1)
 
-	go a.featuredAppIDsAsync(ctx, featuredAppIDsChan, featuredAppIDsErrChan, username, publicAppIDs)
+	go a.db.RecentlyAddedAppsAsync(ctx, recentlyAddedAppsChan, recentlyAddedAppsErrChan, username, a.config.Apps.FavoritesGroupIndex, publicAppIDs, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
 
-	recentlyAddedAppsChan := make(chan []db.App)
-	recentlyAddedAppsErrChan := make(chan error)
+	publicAppsChan := make(chan []db.App, 1)
+	publicAppsErrChan := make(chan error, 1)
 
-	go a.db.RecentlyAddedAppsAsync(ctx, recentlyAddedAppsChan, recentlyAddedAppsErrChan, username, a.config.Apps.FavoritesGroupIndex, publicAppIDs, db.WithQueryLimit(uint(limit)))
+	go a.db.PublicAppsQueryAsync(ctx, publicAppsChan, publicAppsErrChan, username, a.config.Apps.FavoritesGroupIndex, publicAppIDs, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
 
-	publicAppsChan := make(chan []db.App)
-	publicAppsErrChan := make(chan error)
+	recentlyUsedAppsChan := make(chan []db.App, 1)
+	recentlyUsedAppsErrChan := make(chan error, 1)
 
-	go a.db.PublicAppsQueryAsync(ctx, publicAppsChan, publicAppsErrChan, username, a.config.Apps.FavoritesGroupIndex, publicAppIDs, db.WithQueryLimit(uint(limit)))
+	go a.db.RecentlyUsedAppsAsync(ctx, recentlyUsedAppsChan, recentlyUsedAppsErrChan, &db.AppsQueryConfig{
+		Username:          username,
+		GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
+		AppIDs:            publicAppIDs,
+		StartDateInterval: startDateInterval,
+	}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
 
-	recentlyUsedAppsChan := make(chan []db.App)
-	recentlyUsedAppsErrChan := make(chan error)
+	favoriteAppsChan := make(chan []db.App, 1)
+	favoriteAppsErrChan := make(chan error, 1)
 
-	go a.db.RecentlyUsedAppsAsync(ctx, recentlyUsedAppsChan, recentlyUsedAppsErrChan, &db.AppsQueryConfig{
+	go a.db.FavoriteAppsAsync(ctx, favoriteAppsChan, favoriteAppsErrChan, &db.AppsQueryConfig{
+		Username:    username,
+		GroupsIndex: a.config.Apps.FavoritesGroupIndex,
+		AppIDs:      publicAppIDs,
+	}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
+
+	recommendedAppsChan := make(chan []db.App, 1)
+	recommendedAppsErrChan := make(chan error, 1)
+
+	go a.recommendedAppsAsync(ctx, recommendedAppsChan, recommendedAppsErrChan, &db.AppsQueryConfig{
+		Username:          username,
+		GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
+		AppIDs:            publicAppIDs,
+		StartDateInterval: a.config.Apps.RecommendedLookback,
+	}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
+
+	newAppsChan := make(chan []db.App, 1)
+	newAppsErrChan := make(chan error, 1)
+
+	go a.db.NewAppsAsync(ctx, newAppsChan, newAppsErrChan, &db.AppsQueryConfig{
+		Username:          username,
+		GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
+		AppIDs:            publicAppIDs,
+		StartDateInterval: a.normalizeNewInterval(c),
+	}, a.config.Apps.NewEditThreshold, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
+
+	collectionsChan := make(chan map[string][]db.App, 1)
+	collectionsErrChan := make(chan error, 1)
+
+	go a.dashboardCollectionsAsync(ctx, collectionsChan, collectionsErrChan, &db.AppsQueryConfig{
 		Username:          username,
 		GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
 		AppIDs:            publicAppIDs,
 		StartDateInterval: startDateInterval,
-	}, db.WithQueryLimit(uint(limit)))
+	}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
 
-	// We need featured app IDs for the next bit
-	err = <-featuredAppIDsErrChan
-	if err != nil {
-		log.Error(err)
-		return err
+	var (
+		trendingAppsChan    chan []db.App
+		trendingAppsErrChan chan error
+	)
+
+	if a.config.Apps.TrendingOnDashboard {
+		trendingAppsChan = make(chan []db.App, 1)
+		trendingAppsErrChan = make(chan error, 1)
+
+		go a.db.TrendingAppsAsync(ctx, trendingAppsChan, trendingAppsErrChan, &db.AppsQueryConfig{
+			Username:          username,
+			GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
+			AppIDs:            publicAppIDs,
+			StartDateInterval: a.normalizeTrendingInterval(c),
+		}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
+	}
+
+	// The shared apps need both the public and the shared app IDs. The
+	// permissions service isn't needed for anything else on the dashboard, so
+	// the shared section is left empty rather than failing the whole request
+	// if the lookup fails.
+	sharedAppsChan := make(chan []db.App, 1)
+	sharedAppsErrChan := make(chan error, 1)
+
+	if err = <-sharedAppIDsErrChan; err != nil {
+		log.Errorf("unable to look up shared app IDs, leaving the shared section empty: %s", err)
+		sharedAppsErrChan <- nil
+		sharedAppsChan <- []db.App{}
+	} else {
+		sharedAppIDs := <-sharedAppIDsChan
+
+		go a.db.SharedAppsAsync(ctx, sharedAppsChan, sharedAppsErrChan, &db.AppsQueryConfig{
+			Username:    username,
+			GroupsIndex: a.config.Apps.FavoritesGroupIndex,
+			AppIDs:      publicAppIDs,
+		}, sharedAppIDs, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
 	}
-	featuredAppIDs := <-featuredAppIDsChan
 
-	featuredAppsChan := make(chan []db.App)
-	featuredAppsErrChan := make(chan error)
+	featuredAppsChan := make(chan []db.App, 1)
+	featuredAppsErrChan := make(chan error, 1)
 
 	go a.db.PopularFeaturedAppsAsync(ctx, featuredAppsChan, featuredAppsErrChan, &db.AppsQueryConfig{
 		Username:          username,
 		GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
 		AppIDs:            featuredAppIDs,
 		StartDateInterval: startDateInterval,
-	}, db.WithQueryLimit(uint(limit)))
+	}, db.WithQueryLimit(uint(limit)), a.withExpansions(expand...))
 
 	publicFeeds := a.pf
 
 	log.Debug("dereferencing channels")
 
 	// Now, check all the channels we still haven't
-	err = <-ilErrChan
-	if err != nil {
-		log.Error(err)
-		return err
+	// Instant launches are an optional part of the dashboard, so they're left
+	// out rather than failing the whole request.
+	var ilItems []apis.InstantLaunch
+	if err = <-ilErrChan; err != nil {
+		log.Errorf("unable to get instant launches, leaving the section empty: %s", err)
+		ilItems = []apis.InstantLaunch{}
+	} else {
+		ilItems = <-ilChan
 	}
-	ilItems := <-ilChan
 
 	err = <-recentAnalysisErrChan
 	if err != nil {
@@ -157,6 +229,41 @@ func (a *App) UserDashboardHandler(c echo.Context) error {
 	}
 	recentlyUsedApps := <-recentlyUsedAppsChan
 
+	err = <-favoriteAppsErrChan
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	favoriteApps := <-favoriteAppsChan
+
+	err = <-sharedAppsErrChan
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	sharedApps := <-sharedAppsChan
+
+	err = <-recommendedAppsErrChan
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	recommendedApps := <-recommendedAppsChan
+
+	err = <-newAppsErrChan
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	newApps := <-newAppsChan
+
+	err = <-collectionsErrChan
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	collections := <-collectionsChan
+
 	err = <-featuredAppsErrChan
 	if err != nil {
 		log.Error(err)
@@ -164,19 +271,36 @@ func (a *App) UserDashboardHandler(c echo.Context) error {
 	}
 	featuredApps := <-featuredAppsChan
 
+	appSections := map[string]interface{}{
+		"recentlyAdded":   recentlyAddedApps,
+		"public":          publicApps,
+		"recentlyUsed":    recentlyUsedApps,
+		"popularFeatured": featuredApps,
+		"favorites":       favoriteApps,
+		"shared":          sharedApps,
+		"recommended":     recommendedApps,
+		"new":             newApps,
+		"collections":     collections,
+	}
+
+	if a.config.Apps.TrendingOnDashboard {
+		err = <-trendingAppsErrChan
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		appSections["trending"] = <-trendingAppsChan
+	}
+
 	retval := map[string]interface{}{
 		"analyses": map[string]interface{}{
 			"recent":  recentAnalyses.Analyses,
 			"running": runningAnalyses.Analyses,
 		},
-		"apps": map[string]interface{}{
-			"recentlyAdded":   recentlyAddedApps,
-			"public":          publicApps,
-			"recentlyUsed":    recentlyUsedApps,
-			"popularFeatured": featuredApps,
-		},
-		"instantLaunches": ilItems,
-		"feeds":           publicFeeds.Marshallable(ctx),
+		"apps":                 appSections,
+		"instantLaunches":      ilItems,
+		"instantLaunchesCache": a.instantLaunchCacheStatus(),
+		"feeds":                publicFeeds.Marshallable(ctx),
 	}
 
 	if err = c.JSON(http.StatusOK, retval); err != nil {
@@ -192,7 +316,7 @@ func (a *App) PublicAppsForUserHandler(c echo.Context) error {
 
 	ctx := c.Request().Context()
 
-	username, err := normalizeUsername(c)
+	username, err := a.normalizeUsername(c)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -206,6 +330,12 @@ func (a *App) PublicAppsForUserHandler(c echo.Context) error {
 		return err
 	}
 
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
 	publicAppIDs, err := a.publicAppIDs(ctx)
 	if err != nil {
 		log.Error(err)
@@ -218,6 +348,8 @@ func (a *App) PublicAppsForUserHandler(c echo.Context) error {
 		a.config.Apps.FavoritesGroupIndex,
 		publicAppIDs,
 		db.WithQueryLimit(uint(limit)),
+		a.withExpansions(expand...),
+		db.WithCategory(c.QueryParam("category")),
 	)
 	if err != nil {
 		log.Error(err)
@@ -239,7 +371,7 @@ func (a *App) RecentAddedAppsForUserHandler(c echo.Context) error {
 
 	ctx := c.Request().Context()
 
-	username, err := normalizeUsername(c)
+	username, err := a.normalizeUsername(c)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -253,6 +385,12 @@ func (a *App) RecentAddedAppsForUserHandler(c echo.Context) error {
 		return err
 	}
 
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
 	publicAppIDs, err := a.publicAppIDs(ctx)
 	if err != nil {
 		log.Error(err)
@@ -265,6 +403,7 @@ func (a *App) RecentAddedAppsForUserHandler(c echo.Context) error {
 		a.config.Apps.FavoritesGroupIndex,
 		publicAppIDs,
 		db.WithQueryLimit(uint(limit)),
+		a.withExpansions(expand...),
 	)
 	if err != nil {
 		log.Error(err)
@@ -286,7 +425,7 @@ func (a *App) PopularFeaturedAppsForUserHandler(c echo.Context) error {
 
 	ctx := c.Request().Context()
 
-	username, err := normalizeUsername(c)
+	username, err := a.normalizeUsername(c)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -300,15 +439,27 @@ func (a *App) PopularFeaturedAppsForUserHandler(c echo.Context) error {
 		return err
 	}
 
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
 	startDateInterval := normalizeStartDateInterval(c)
 
+	sortBy, err := normalizePopularitySort(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
 	publicAppIDs, err := a.publicAppIDs(ctx)
 	if err != nil {
 		log.Error(err)
 		return err
 	}
 
-	featuredAppIDs, err := a.featuredAppIDs(ctx, username, publicAppIDs)
+	featuredAppIDs, err := a.featuredAppIDs(ctx, publicAppIDs)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -321,8 +472,10 @@ func (a *App) PopularFeaturedAppsForUserHandler(c echo.Context) error {
 			GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
 			AppIDs:            featuredAppIDs,
 			StartDateInterval: startDateInterval,
+			SortBy:            sortBy,
 		},
 		db.WithQueryLimit(uint(limit)),
+		a.withExpansions(expand...),
 	)
 	if err != nil {
 		log.Error(err)
@@ -344,7 +497,7 @@ func (a *App) RecentlyUsedAppsForUser(c echo.Context) error {
 
 	ctx := c.Request().Context()
 
-	username, err := normalizeUsername(c)
+	username, err := a.normalizeUsername(c)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -358,6 +511,12 @@ func (a *App) RecentlyUsedAppsForUser(c echo.Context) error {
 		return err
 	}
 
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
 	startDateInterval := normalizeStartDateInterval(c)
 
 	publicAppIDs, err := a.publicAppIDs(ctx)
@@ -375,6 +534,7 @@ func (a *App) RecentlyUsedAppsForUser(c echo.Context) error {
 			StartDateInterval: startDateInterval,
 		},
 		db.WithQueryLimit(uint(limit)),
+		a.withExpansions(expand...),
 	)
 	if err != nil {
 		log.Error(err)
@@ -390,3 +550,200 @@ func (a *App) RecentlyUsedAppsForUser(c echo.Context) error {
 
 	return nil
 }
+
+func (a *App) FavoriteAppsForUserHandler(c echo.Context) error {
+	log := log.WithField("context", "favorite apps for user")
+
+	ctx := c.Request().Context()
+
+	username, err := a.normalizeUsername(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log = log.WithField("user", username)
+
+	limit, err := normalizeLimit(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	offset, err := normalizeOffset(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	publicAppIDs, err := a.publicAppIDs(ctx)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	favoriteApps, err := a.db.FavoriteApps(
+		ctx,
+		&db.AppsQueryConfig{
+			Username:    username,
+			GroupsIndex: a.config.Apps.FavoritesGroupIndex,
+			AppIDs:      publicAppIDs,
+		},
+		db.WithQueryLimit(uint(limit)),
+		a.withExpansions(expand...),
+		db.WithQueryOffset(uint(offset)),
+	)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	if err = c.JSON(http.StatusOK, map[string][]db.App{
+		"apps": favoriteApps,
+	}); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *App) SharedAppsForUserHandler(c echo.Context) error {
+	log := log.WithField("context", "shared apps for user")
+
+	ctx := c.Request().Context()
+
+	username, err := a.normalizeUsername(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log = log.WithField("user", username)
+
+	limit, err := normalizeLimit(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	offset, err := normalizeOffset(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	publicAppIDs, err := a.publicAppIDs(ctx)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	sharedAppIDs, err := a.sharedAppIDs(ctx, username)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	sharedApps, err := a.db.SharedApps(
+		ctx,
+		&db.AppsQueryConfig{
+			Username:    username,
+			GroupsIndex: a.config.Apps.FavoritesGroupIndex,
+			AppIDs:      publicAppIDs,
+		},
+		sharedAppIDs,
+		db.WithQueryLimit(uint(limit)),
+		a.withExpansions(expand...),
+		db.WithQueryOffset(uint(offset)),
+	)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	if err = c.JSON(http.StatusOK, map[string][]db.App{
+		"apps": sharedApps,
+	}); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (a *App) RecommendedAppsForUserHandler(c echo.Context) error {
+	log := log.WithField("context", "recommended apps for user")
+
+	ctx := c.Request().Context()
+
+	username, err := a.normalizeUsername(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	log = log.WithField("user", username)
+
+	limit, err := normalizeLimit(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	expand, err := normalizeExpand(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	offset, err := normalizeOffset(c)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	publicAppIDs, err := a.publicAppIDs(ctx)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	recommendedApps, err := a.recommendedApps(
+		ctx,
+		&db.AppsQueryConfig{
+			Username:          username,
+			GroupsIndex:       a.config.Apps.FavoritesGroupIndex,
+			AppIDs:            publicAppIDs,
+			StartDateInterval: a.config.Apps.RecommendedLookback,
+		},
+		db.WithQueryLimit(uint(limit)),
+		a.withExpansions(expand...),
+		db.WithQueryOffset(uint(offset)),
+	)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	if err = c.JSON(http.StatusOK, map[string][]db.App{
+		"apps": recommendedApps,
+	}); err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cyverse-de/dashboard-aggregator/config"
+	"github.com/cyverse-de/go-mod/logging"
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+var log = logging.Log.WithField("package", "auth")
+var httpClient = http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+
+// identityKey is the key the caller's Identity is stored under in the Echo
+// context.
+const identityKey = "identity"
+
+// minRefreshInterval limits how often the JWKS is re-fetched when a token is
+// signed with an unknown key.
+const minRefreshInterval = time.Minute
+
+// Identity describes the authenticated caller of an endpoint.
+type Identity struct {
+	Username string
+	Roles    []string
+}
+
+// HasAnyRole returns true if the identity carries at least one of the roles.
+func (i *Identity) HasAnyRole(roles []string) bool {
+	for _, want := range roles {
+		for _, have := range i.Roles {
+			if want == have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IdentityFromContext returns the identity stored in the Echo context by the
+// Authenticator middleware, or nil if there isn't one.
+func IdentityFromContext(c echo.Context) *Identity {
+	identity, ok := c.Get(identityKey).(*Identity)
+	if !ok {
+		return nil
+	}
+	return identity
+}
+
+// Authenticator determines the identity of callers, either from a bearer JWT
+// signed by one of the keys in the configured JWKS or from headers set by a
+// trusted gateway.
+type Authenticator struct {
+	cfg         *config.AuthConfiguration
+	parser      *jwt.Parser
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	lastRefresh time.Time // the last attempt to load the keys, successful or not
+}
+
+// New returns a new *Authenticator, loading the JWKS if one is configured.
+func New(ctx context.Context, cfg *config.AuthConfiguration) (*Authenticator, error) {
+	a := &Authenticator{
+		cfg:         cfg,
+		parser:      &jwt.Parser{ValidMethods: []string{"RS256", "RS384", "RS512"}},
+		keys:        make(map[string]*rsa.PublicKey),
+		lastRefresh: time.Now(),
+	}
+	if err := a.loadKeys(ctx); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *Authenticator) loadKeys(ctx context.Context) error {
+	var (
+		keys map[string]*rsa.PublicKey
+		err  error
+	)
+
+	switch {
+	case a.cfg.JWKSFile != "":
+		keys, err = readJWKSFile(a.cfg.JWKSFile)
+	case a.cfg.JWKSURL != "":
+		keys, err = fetchJWKS(ctx, a.cfg.JWKSURL)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.keys = keys
+	a.mu.Unlock()
+
+	return nil
+}
+
+// startRefresh returns true if the keys may be re-fetched now, recording the
+// attempt so that other callers are throttled whether or not it succeeds.
+func (a *Authenticator) startRefresh() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if time.Since(a.lastRefresh) <= minRefreshInterval {
+		return false
+	}
+	a.lastRefresh = time.Now()
+	return true
+}
+
+// key returns the public key with the given ID. If the key isn't known and
+// the keys come from a URL, the JWKS is re-fetched in case the keys were
+// rotated, at most once every minRefreshInterval.
+func (a *Authenticator) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	a.mu.RLock()
+	key, ok := a.keys[kid]
+	if !ok && kid == "" && len(a.keys) == 1 {
+		for _, k := range a.keys {
+			key, ok = k, true
+		}
+	}
+	a.mu.RUnlock()
+
+	if ok {
+		return key, nil
+	}
+
+	if a.cfg.JWKSURL != "" && a.startRefresh() {
+		log.Infof("refreshing the JWKS from %s", a.cfg.JWKSURL)
+		if err := a.loadKeys(ctx); err != nil {
+			return nil, err
+		}
+		a.mu.RLock()
+		key, ok = a.keys[kid]
+		a.mu.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	v, _ := claims[name].(string)
+	return v
+}
+
+func rolesClaim(claims jwt.MapClaims) []string {
+	var roles []string
+
+	add := func(v interface{}) {
+		list, _ := v.([]interface{})
+		for _, r := range list {
+			if s, ok := r.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+	}
+
+	if realmAccess, ok := claims["realm_access"].(map[string]interface{}); ok {
+		add(realmAccess["roles"])
+	}
+	add(claims["roles"])
+
+	return roles
+}
+
+// identityFromToken validates a bearer token and returns the identity it
+// asserts.
+func (a *Authenticator) identityFromToken(ctx context.Context, tokenString string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := a.parser.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, errors.New("token is missing the exp claim or has expired")
+	}
+	if a.cfg.Issuer != "" && !claims.VerifyIssuer(a.cfg.Issuer, true) {
+		return nil, errors.New("token has an invalid issuer")
+	}
+	if a.cfg.Audience != "" && !claims.VerifyAudience(a.cfg.Audience, true) {
+		return nil, errors.New("token has an invalid audience")
+	}
+
+	username := stringClaim(claims, a.cfg.UsernameClaim)
+	if username == "" {
+		return nil, fmt.Errorf("token is missing the %s claim", a.cfg.UsernameClaim)
+	}
+
+	return &Identity{
+		Username: username,
+		Roles:    rolesClaim(claims),
+	}, nil
+}
+
+// identityFromGateway returns the identity asserted by the trusted gateway
+// headers, or nil if they aren't configured or present. The gateway must strip
+// these headers from incoming requests.
+func (a *Authenticator) identityFromGateway(r *http.Request) *Identity {
+	if a.cfg.GatewayUserHeader == "" {
+		return nil
+	}
+
+	username := r.Header.Get(a.cfg.GatewayUserHeader)
+	if username == "" {
+		return nil
+	}
+
+	var roles []string
+	if a.cfg.GatewayRolesHeader != "" {
+		for _, r := range strings.Split(r.Header.Get(a.cfg.GatewayRolesHeader), ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				roles = append(roles, r)
+			}
+		}
+	}
+
+	return &Identity{
+		Username: username,
+		Roles:    roles,
+	}
+}
+
+// IsAdmin returns true if the identity carries one of the configured admin
+// roles.
+func (a *Authenticator) IsAdmin(identity *Identity) bool {
+	return identity.HasAnyRole(a.cfg.AdminRoles)
+}
+
+// Middleware returns Echo middleware that rejects requests without a valid
+// identity and stores the identity in the context for the handlers.
+func (a *Authenticator) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			log := log.WithField("context", "authentication")
+
+			var identity *Identity
+
+			header := c.Request().Header.Get(echo.HeaderAuthorization)
+			if tokenString, found := strings.CutPrefix(header, "Bearer "); found {
+				if a.cfg.JWKSFile == "" && a.cfg.JWKSURL == "" {
+					return echo.NewHTTPError(http.StatusUnauthorized, "bearer tokens are not accepted")
+				}
+				var err error
+				identity, err = a.identityFromToken(c.Request().Context(), strings.TrimSpace(tokenString))
+				if err != nil {
+					log.Debug(err)
+					return echo.NewHTTPError(http.StatusUnauthorized, "invalid bearer token")
+				}
+			} else {
+				identity = a.identityFromGateway(c.Request())
+			}
+
+			if identity == nil {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return echo.NewHTTPError(http.StatusUnauthorized, "authentication is required")
+			}
+
+			c.Set(identityKey, identity)
+
+			return next(c)
+		}
+	}
+}
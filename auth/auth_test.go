@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cyverse-de/dashboard-aggregator/config"
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	testKeyID    = "test-key"
+	testIssuer   = "https://keycloak.example.org/realms/CyVerse"
+	testAudience = "de"
+)
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func jwksJSON(t *testing.T, kid string, key *rsa.PublicKey) []byte {
+	t.Helper()
+	b, err := json.Marshal(jwkSet{Keys: []jwk{{
+		KeyID:     kid,
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: "RS256",
+		Modulus:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func writeJWKSFile(t *testing.T, key *rsa.PublicKey) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksJSON(t, testKeyID, key), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                testIssuer,
+		"aud":                testAudience,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"preferred_username": "ipcdev",
+		"realm_access":       map[string]interface{}{"roles": []string{"de-admins"}},
+	}
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newTestAuthenticator(t *testing.T, cfg *config.AuthConfiguration) *Authenticator {
+	t.Helper()
+	a, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// serve sends a request through the middleware and returns the response
+// recorder along with the identity the handler saw, if any.
+func serve(a *Authenticator, header http.Header) (*httptest.ResponseRecorder, *Identity) {
+	e := echo.New()
+	var identity *Identity
+	e.GET("/", func(c echo.Context) error {
+		identity = IdentityFromContext(c)
+		return c.NoContent(http.StatusOK)
+	}, a.Middleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec, identity
+}
+
+func bearer(token string) http.Header {
+	return http.Header{echo.HeaderAuthorization: []string{"Bearer " + token}}
+}
+
+func TestMiddlewareTokens(t *testing.T) {
+	key := generateKey(t)
+	otherKey := generateKey(t)
+
+	a := newTestAuthenticator(t, &config.AuthConfiguration{
+		Enabled:       true,
+		JWKSFile:      writeJWKSFile(t, &key.PublicKey),
+		Issuer:        testIssuer,
+		Audience:      testAudience,
+		UsernameClaim: "preferred_username",
+	})
+
+	withClaim := func(name string, value interface{}) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	hs256 := func() string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	none := func() string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"valid RS256", signRS256(t, key, testKeyID, validClaims()), http.StatusOK},
+		{"HS256", hs256(), http.StatusUnauthorized},
+		{"alg none", none(), http.StatusUnauthorized},
+		{"unknown kid", signRS256(t, key, "unknown", validClaims()), http.StatusUnauthorized},
+		{"wrong key", signRS256(t, otherKey, testKeyID, validClaims()), http.StatusUnauthorized},
+		{"expired", signRS256(t, key, testKeyID, withClaim("exp", time.Now().Add(-time.Minute).Unix())), http.StatusUnauthorized},
+		{"missing exp", signRS256(t, key, testKeyID, withClaim("exp", nil)), http.StatusUnauthorized},
+		{"wrong issuer", signRS256(t, key, testKeyID, withClaim("iss", "https://evil.example.org")), http.StatusUnauthorized},
+		{"wrong audience", signRS256(t, key, testKeyID, withClaim("aud", "other")), http.StatusUnauthorized},
+		{"missing username", signRS256(t, key, testKeyID, withClaim("preferred_username", nil)), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, identity := serve(a, bearer(tt.token))
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if identity == nil || identity.Username != "ipcdev" {
+				t.Fatalf("unexpected identity %+v", identity)
+			}
+			if len(identity.Roles) != 1 || identity.Roles[0] != "de-admins" {
+				t.Fatalf("unexpected roles %v", identity.Roles)
+			}
+		})
+	}
+}
+
+func TestMiddlewareGatewayHeaders(t *testing.T) {
+	key := generateKey(t)
+	spoofed := http.Header{"X-Remote-User": []string{"admin"}}
+
+	t.Run("not trusted", func(t *testing.T) {
+		a := newTestAuthenticator(t, &config.AuthConfiguration{
+			Enabled:       true,
+			JWKSFile:      writeJWKSFile(t, &key.PublicKey),
+			UsernameClaim: "preferred_username",
+		})
+		rec, identity := serve(a, spoofed)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+		if identity != nil {
+			t.Fatalf("unexpected identity %+v", identity)
+		}
+	})
+
+	t.Run("trusted", func(t *testing.T) {
+		a := newTestAuthenticator(t, &config.AuthConfiguration{
+			Enabled:            true,
+			UsernameClaim:      "preferred_username",
+			GatewayUserHeader:  "X-Remote-User",
+			GatewayRolesHeader: "X-Remote-Roles",
+		})
+		header := spoofed.Clone()
+		header.Set("X-Remote-Roles", "de-admins, de-users")
+		rec, identity := serve(a, header)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if identity == nil || identity.Username != "admin" || len(identity.Roles) != 2 {
+			t.Fatalf("unexpected identity %+v", identity)
+		}
+	})
+
+	t.Run("bearer tokens without keys", func(t *testing.T) {
+		a := newTestAuthenticator(t, &config.AuthConfiguration{
+			Enabled:           true,
+			UsernameClaim:     "preferred_username",
+			GatewayUserHeader: "X-Remote-User",
+		})
+		rec, _ := serve(a, bearer(signRS256(t, key, testKeyID, validClaims())))
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+	})
+}
+
+func TestKeyRefreshThrottledOnFailure(t *testing.T) {
+	key := generateKey(t)
+
+	var fetches, failing atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if failing.Load() != 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(jwksJSON(t, testKeyID, &key.PublicKey))
+	}))
+	defer srv.Close()
+
+	a := newTestAuthenticator(t, &config.AuthConfiguration{
+		Enabled:       true,
+		JWKSURL:       srv.URL,
+		UsernameClaim: "preferred_username",
+	})
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("expected 1 fetch at startup, got %d", n)
+	}
+
+	// Unknown keys right after a load don't trigger a refresh.
+	if _, err := a.key(context.Background(), "rotated"); err == nil {
+		t.Fatal("expected an error for an unknown key")
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("expected no refresh within the throttle interval, got %d fetches", n)
+	}
+
+	// Once the interval has passed, a single failed refresh still counts
+	// against the throttle.
+	failing.Store(1)
+	a.mu.Lock()
+	a.lastRefresh = time.Now().Add(-2 * minRefreshInterval)
+	a.mu.Unlock()
+
+	for i := 0; i < 5; i++ {
+		if _, err := a.key(context.Background(), "rotated"); err == nil {
+			t.Fatal("expected an error for an unknown key")
+		}
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("expected exactly one refresh attempt, got %d fetches", n-1)
+	}
+
+	// The known key is still served after the failed refresh.
+	if _, err := a.key(context.Background(), testKeyID); err != nil {
+		t.Fatal(err)
+	}
+}
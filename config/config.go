@@ -2,7 +2,9 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/cyverse-de/go-mod/logging"
 	"github.com/knadh/koanf"
@@ -74,10 +76,89 @@ func NewLoggingConfiguration(config *koanf.Koanf) *LoggingConfiguration {
 	}
 }
 
+// FeaturedCollectionName is the name of the collection defined by the
+// metadata.featured_apps_attr and metadata.featured_apps_value settings.
+const FeaturedCollectionName = "featured"
+
+// AVU is an attribute and value pair used to look up items in the metadata
+// service.
+type AVU struct {
+	Attribute string
+	Value     string
+}
+
+// CollectionConfiguration defines a named collection of apps. Apps are in the
+// collection if they have any of the AVUs.
+type CollectionConfiguration struct {
+	Name      string
+	AVUs      []AVU
+	Dashboard bool
+}
+
 type MetadataConfiguration struct {
 	URL                   string
 	FeaturedAppsAttribute string
 	FeaturedAppsValue     string
+	Collections           []CollectionConfiguration
+
+	// HierarchyAttributes are the attributes used to tag apps with classes in
+	// the app ontology hierarchies.
+	HierarchyAttributes []string
+}
+
+// Collection returns the collection with the given name.
+func (m *MetadataConfiguration) Collection(name string) (*CollectionConfiguration, bool) {
+	for i := range m.Collections {
+		if m.Collections[i].Name == name {
+			return &m.Collections[i], true
+		}
+	}
+	return nil, false
+}
+
+// newCollectionConfigurations returns the collections listed under
+// metadata.collections, preceded by the featured collection.
+func newCollectionConfigurations(config *koanf.Koanf, featured AVU) ([]CollectionConfiguration, error) {
+	collections := []CollectionConfiguration{
+		{
+			Name:      FeaturedCollectionName,
+			AVUs:      []AVU{featured},
+			Dashboard: false,
+		},
+	}
+
+	for _, c := range config.Slices("metadata.collections") {
+		name := c.String("name")
+		if name == "" {
+			return nil, errors.New("metadata.collections entries must have a name")
+		}
+		for _, existing := range collections {
+			if existing.Name == name {
+				return nil, fmt.Errorf("metadata.collections contains more than one collection named %s", name)
+			}
+		}
+
+		var avus []AVU
+		for _, avu := range c.Slices("avus") {
+			a := avu.String("attr")
+			v := avu.String("value")
+			if a == "" || v == "" {
+				return nil, fmt.Errorf("the avus for collection %s must have an attr and a value", name)
+			}
+			avus = append(avus, AVU{Attribute: a, Value: v})
+		}
+		if len(avus) == 0 {
+			return nil, fmt.Errorf("collection %s must have at least one avu", name)
+		}
+
+		collections = append(collections, CollectionConfiguration{
+			Name:      name,
+			AVUs:      avus,
+			Dashboard: c.Bool("dashboard"),
+		})
+	}
+
+	return collections, nil
 }
 
 func NewMetadataConfiguration(config *koanf.Koanf) (*MetadataConfiguration, error) {
@@ -93,16 +174,54 @@ func NewMetadataConfiguration(config *koanf.Koanf) (*MetadataConfiguration, erro
 	if v == "" {
 		return nil, errors.New("metadata.featured_apps_value must be set in the configuration")
 	}
+	collections, err := newCollectionConfigurations(config, AVU{Attribute: a, Value: v})
+	if err != nil {
+		return nil, err
+	}
+	h := config.Strings("metadata.hierarchy_attrs")
+	if len(h) == 0 {
+		h = []string{"rdf:type"}
+	}
 	return &MetadataConfiguration{
 		URL:                   u,
 		FeaturedAppsAttribute: a,
 		FeaturedAppsValue:     v,
+		Collections:           collections,
+		HierarchyAttributes:   h,
 	}, nil
 }
 
 type AppExposerConfiguration struct {
 	URL  string
 	User string
+
+	// InstantLaunchAttribute and InstantLaunchValue select the instant
+	// launches shown on the dashboards by their metadata.
+	InstantLaunchAttribute string
+	InstantLaunchValue     string
+
+	// UserInstantLaunches adds each user's own instant launches to the ones
+	// selected by metadata on their dashboard.
+	UserInstantLaunches bool
+
+	// InstantLaunchRefreshInterval is how often the cached instant launches
+	// selected by metadata are refreshed.
+	InstantLaunchRefreshInterval time.Duration
+
+	// LoggedOutInstantLaunches shows the public instant launches on the
+	// logged-out dashboard.
+	LoggedOutInstantLaunches bool
+
+	// InstantLaunchURL is the template for instant launch links. The {id}
+	// placeholder is replaced with the instant launch ID. Links are left out
+	// if it's empty.
+	InstantLaunchURL string
+
+	// InstantLaunchLoginURL is the template for the instant launch links on
+	// the logged-out dashboard, which send visitors through the login page.
+	// The {redirect} placeholder is replaced with the escaped instant launch
+	// link.
+	InstantLaunchLoginURL string
 }
 
 func NewAppExposerConfiguration(config *koanf.Koanf) (*AppExposerConfiguration, error) {
@@ -114,17 +233,65 @@ func NewAppExposerConfiguration(config *koanf.Koanf) (*AppExposerConfiguration,
 	if au == "" {
 		return nil, errors.New("app-exposer.user must be set in the configuration")
 	}
+	ila := config.String("app-exposer.instant_launches.attribute")
+	if ila == "" {
+		ila = "ui_location"
+	}
+	ilv := config.String("app-exposer.instant_launches.value")
+	if ilv == "" {
+		ilv = "dashboard"
+	}
+	ilr := config.Duration("app-exposer.instant_launches.refresh_interval")
+	if ilr <= 0 {
+		ilr = 15 * time.Minute
+	}
+	ilo := true
+	if config.Exists("app-exposer.instant_launches.logged_out") {
+		ilo = config.Bool("app-exposer.instant_launches.logged_out")
+	}
+	ilu := config.String("app-exposer.instant_launches.launch_url")
+	if ilu == "" {
+		ilu = "/instantlaunch/{id}"
+	}
+	ill := config.String("app-exposer.instant_launches.login_url")
+	if ill == "" {
+		ill = "/login?redirect={redirect}"
+	}
 	return &AppExposerConfiguration{
-		URL:  u,
-		User: au,
+		URL:                          u,
+		User:                         au,
+		InstantLaunchAttribute:       ila,
+		InstantLaunchValue:           ilv,
+		UserInstantLaunches:          config.Bool("app-exposer.instant_launches.per_user"),
+		InstantLaunchRefreshInterval: ilr,
+		LoggedOutInstantLaunches:     ilo,
+		InstantLaunchURL:             ilu,
+		InstantLaunchLoginURL:        ill,
 	}, nil
 }
 
+// Types of persistent feed caches.
+const (
+	FeedCacheNone     = ""
+	FeedCacheFile     = "file"
+	FeedCacheDatabase = "database"
+)
+
 type FeedsConfiguration struct {
 	WebsiteURL    string
 	NewsFeedURL   string
 	EventsFeedURL string
 	VideosURL     string
+
+	// CacheType determines where the last good feed items are persisted, if
+	// anywhere. CachePath is the file used by the file cache.
+	CacheType string
+	CachePath string
+
+	// CacheCreateTable lets the service create the feed items table in the DE
+	// database at startup when the database cache is used. It's off by
+	// default, in which case the table must already exist.
+	CacheCreateTable bool
 }
 
 func NewFeedsConfiguration(config *koanf.Koanf) (*FeedsConfiguration, error) {
@@ -152,17 +319,38 @@ func NewFeedsConfiguration(config *koanf.Koanf) (*FeedsConfiguration, error) {
 	if err != nil {
 		return nil, err
 	}
+	cacheType := config.String("feeds.cache.type")
+	cachePath := config.String("feeds.cache.path")
+	switch cacheType {
+	case FeedCacheNone, FeedCacheDatabase:
+	case FeedCacheFile:
+		if cachePath == "" {
+			return nil, errors.New("feeds.cache.path must be set in the configuration when feeds.cache.type is file")
+		}
+	default:
+		return nil, fmt.Errorf("feeds.cache.type must be %s or %s", FeedCacheFile, FeedCacheDatabase)
+	}
 	return &FeedsConfiguration{
-		WebsiteURL:    websiteBase,
-		NewsFeedURL:   newsURL,
-		EventsFeedURL: eventsURL,
-		VideosURL:     videosURL,
+		WebsiteURL:       websiteBase,
+		NewsFeedURL:      newsURL,
+		EventsFeedURL:    eventsURL,
+		VideosURL:        videosURL,
+		CacheType:        cacheType,
+		CachePath:        cachePath,
+		CacheCreateTable: config.Bool("feeds.cache.create_table"),
 	}, nil
 }
 
 type AppsConfiguration struct {
 	URL                 string
 	FavoritesGroupIndex int
+	TrendingInterval    string
+	TrendingOnDashboard bool
+	RecommendedLookback string
+	RecommendedCacheTTL time.Duration
+	NewInterval         string
+	NewEditThreshold    string
+	IDRefreshInterval   time.Duration
 }
 
 func NewAppsConfiguration(config *koanf.Koanf) (*AppsConfiguration, error) {
@@ -174,9 +362,40 @@ func NewAppsConfiguration(config *koanf.Koanf) (*AppsConfiguration, error) {
 	if i == 0 {
 		i = 10
 	}
+	t := config.String("apps.trending.interval")
+	if t == "" {
+		t = "7 days"
+	}
+	r := config.String("apps.recommended.lookback")
+	if r == "" {
+		r = "90 days"
+	}
+	rt := config.Duration("apps.recommended.cache_ttl")
+	if rt <= 0 {
+		rt = time.Hour
+	}
+	n := config.String("apps.new.interval")
+	if n == "" {
+		n = "7 days"
+	}
+	et := config.String("apps.new.edit_threshold")
+	if et == "" {
+		et = "24 hours"
+	}
+	d := config.Duration("apps.id_refresh_interval")
+	if d <= 0 {
+		d = 5 * time.Minute
+	}
 	return &AppsConfiguration{
 		URL:                 u,
 		FavoritesGroupIndex: i,
+		TrendingInterval:    t,
+		TrendingOnDashboard: config.Bool("apps.trending.dashboard"),
+		RecommendedLookback: r,
+		RecommendedCacheTTL: rt,
+		NewInterval:         n,
+		NewEditThreshold:    et,
+		IDRefreshInterval:   d,
 	}, nil
 
 }
@@ -212,6 +431,60 @@ func NewPermissionsConfiguration(config *koanf.Koanf) (*PermissionsConfiguration
 	}, nil
 }
 
+type UsersConfiguration struct {
+	Domain string
+}
+
+func NewUsersConfiguration(config *koanf.Koanf) *UsersConfiguration {
+	d := config.String("users.domain")
+	if d == "" {
+		d = "iplantcollaborative.org"
+	}
+	return &UsersConfiguration{
+		Domain: d,
+	}
+}
+
+type AuthConfiguration struct {
+	Enabled            bool
+	JWKSFile           string
+	JWKSURL            string
+	Issuer             string
+	Audience           string
+	UsernameClaim      string
+	AdminRoles         []string
+	GatewayUserHeader  string
+	GatewayRolesHeader string
+}
+
+func NewAuthConfiguration(config *koanf.Koanf) (*AuthConfiguration, error) {
+	e := config.Bool("auth.enabled")
+	f := config.String("auth.jwks_file")
+	u := config.String("auth.jwks_url")
+	h := config.String("auth.gateway.user_header")
+	if e && f == "" && u == "" && h == "" {
+		return nil, errors.New("auth.jwks_file, auth.jwks_url, or auth.gateway.user_header must be set when auth.enabled is true")
+	}
+	if f != "" && u != "" {
+		return nil, errors.New("only one of auth.jwks_file and auth.jwks_url may be set in the configuration")
+	}
+	c := config.String("auth.username_claim")
+	if c == "" {
+		c = "preferred_username"
+	}
+	return &AuthConfiguration{
+		Enabled:            e,
+		JWKSFile:           f,
+		JWKSURL:            u,
+		Issuer:             config.String("auth.issuer"),
+		Audience:           config.String("auth.audience"),
+		UsernameClaim:      c,
+		AdminRoles:         config.Strings("auth.admin_roles"),
+		GatewayUserHeader:  h,
+		GatewayRolesHeader: config.String("auth.gateway.roles_header"),
+	}, nil
+}
+
 // ServiceConfiguration is the type all other configuration types are included
 // in.
 type ServiceConfiguration struct {
@@ -222,6 +495,8 @@ type ServiceConfiguration struct {
 	Metadata    *MetadataConfiguration
 	Apps        *AppsConfiguration
 	Permissions *PermissionsConfiguration
+	Users       *UsersConfiguration
+	Auth        *AuthConfiguration
 	ListenPort  int
 }
 
@@ -251,6 +526,11 @@ func New(config *koanf.Koanf) (*ServiceConfiguration, error) {
 	if err != nil {
 		return nil, err
 	}
+	usersConfig := NewUsersConfiguration(config)
+	authConfig, err := NewAuthConfiguration(config)
+	if err != nil {
+		return nil, err
+	}
 	listenPort := config.Int("listen_port")
 	if listenPort == 0 {
 		listenPort = 60000
@@ -263,6 +543,8 @@ func New(config *koanf.Koanf) (*ServiceConfiguration, error) {
 		Metadata:    mdConfig,
 		Apps:        appsConfig,
 		Permissions: permissionsConfig,
+		Users:       usersConfig,
+		Auth:        authConfig,
 		ListenPort:  listenPort,
 	}, nil
 }
@@ -4,418 +4,184 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/doug-martin/goqu/v9"
-	"github.com/lib/pq"
 	"go.opentelemetry.io/otel"
 )
 
+// PopularitySort determines how apps are ranked by popularity.
+type PopularitySort string
+
+const (
+	// SortByJobs ranks apps by the number of jobs run.
+	SortByJobs PopularitySort = "jobs"
+
+	// SortByUsers ranks apps by the number of distinct users that ran them.
+	SortByUsers PopularitySort = "users"
+
+	// SortByScore ranks apps by the number of distinct users plus the log of
+	// the number of jobs, so additional jobs from the same users count for
+	// less and less.
+	SortByScore PopularitySort = "score"
+)
+
+// ParsePopularitySort returns the PopularitySort with the given name.
+func ParsePopularitySort(s string) (PopularitySort, error) {
+	switch p := PopularitySort(s); p {
+	case SortByJobs, SortByUsers, SortByScore:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown sort %q; must be one of %s, %s, or %s", s, SortByJobs, SortByUsers, SortByScore)
+	}
+}
+
 type AppsQueryConfig struct {
 	Username          string
 	GroupsIndex       int
 	AppIDs            []string
 	StartDateInterval string
+	SortBy            PopularitySort
 }
 
+// PopularFeaturedApps returns the apps in cfg.AppIDs ranked by their usage
+// during cfg.StartDateInterval.
 func (d *Database) PopularFeaturedApps(ctx context.Context, cfg *AppsQueryConfig, opts ...QueryOption) ([]App, error) {
 	ctx, span := otel.Tracer(otelName).Start(ctx, "PopularFeaturedApps")
 	defer span.End()
 
-	var (
-		err  error
-		db   GoquDatabase
-		apps []App
-	)
-
-	querySettings := &QuerySettings{}
-	for _, opt := range opts {
-		opt(querySettings)
-	}
-
-	if querySettings.tx != nil {
-		db = querySettings.tx
-	} else {
-		db = d.goquDB
-	}
-
-	a := goqu.T("app_listing")
-	j := goqu.T("jobs")
-	u := goqu.T("users")
-	w := goqu.T("workspace")
-	acg := goqu.T("app_category_group")
-	aca := goqu.T("app_category_app")
-
-	subquery := db.From(u).
-		Join(w, goqu.On(u.Col("id").Eq(w.Col("user_id")))).
-		Join(acg, goqu.On(w.Col("root_category_id").Eq(acg.Col("parent_category_id")))).
-		Join(aca, goqu.On(acg.Col("child_category_id").Eq(aca.Col("app_category_id")))).
-		Where(
-			u.Col("username").Eq(cfg.Username),
-			acg.Col("child_index").Eq(cfg.GroupsIndex),
-			aca.Col("app_id").Eq(a.Col("id")),
-		)
-
-	query := db.From(a).
-		Select(
-			a.Col("id"),
-			goqu.L(`'de'`).As("system_id"),
-			a.Col("name"),
-			a.Col("description"),
-			a.Col("wiki_url"),
-			a.Col("integration_date"),
-			a.Col("edited_date"),
-			a.Col("integrator_username").As(goqu.C("username")),
-			goqu.COUNT(j.Col("id")).As(goqu.C("job_count")),
-			goqu.L("EXISTS(?)", subquery).As(goqu.C("is_favorite")),
-			goqu.L("true").As(goqu.C("is_public")),
-		).
-		LeftJoin(j, goqu.On(j.Col("app_id").Eq(goqu.Cast(a.Col("id"), "TEXT")))).
-		Where(
-			a.Col("id").Eq(goqu.Any(pq.Array(cfg.AppIDs))),
-			a.Col("deleted").Eq(goqu.L("false")),
-			a.Col("disabled").Eq(goqu.L("false")),
-			a.Col("integration_date").IsNotNull(),
-			goqu.Or(
-				j.Col("start_date").Gte(goqu.L("now() - ?", goqu.Cast(goqu.L(fmt.Sprintf("'%s'", cfg.StartDateInterval)), "interval"))),
-				j.Col("start_date").IsNull(),
-			),
-		).
-		GroupBy(
-			a.Col("id"),
-			a.Col("name"),
-			a.Col("description"),
-			a.Col("wiki_url"),
-			a.Col("integration_date"),
-			a.Col("edited_date"),
-			a.Col("integrator_username"),
-		).
-		Order(
-			goqu.C("job_count").Desc(),
-		)
-
-	if querySettings.hasLimit {
-		query = query.Limit(querySettings.limit)
-	}
-
-	if querySettings.hasOffset {
-		query = query.Offset(querySettings.offset)
-	}
-
-	executor := query.Executor()
-
-	apps = make([]App, 0)
-	if err = executor.ScanStructsContext(ctx, &apps); err != nil {
-		return nil, err
-	}
+	return d.popularFeaturedAppsQuery(cfg).Run(ctx, opts...)
+}
 
-	return apps, err
+func (d *Database) popularFeaturedAppsQuery(cfg *AppsQueryConfig) *AppQuery {
+	return d.NewAppQuery("popular featured apps", cfg.Username, cfg.GroupsIndex).
+		PublicOnly(cfg.AppIDs).
+		IntegratedOnly().
+		WithJobs(JobsFilter{Since: cfg.StartDateInterval}).
+		RankBy(cfg.SortBy.Ranking())
 }
 
 func (d *Database) PopularFeaturedAppsAsync(ctx context.Context, appsChan chan []App, errChan chan error, cfg *AppsQueryConfig, opts ...QueryOption) {
-	log.Debug("getting popular featured apps")
-	apps, err := d.PopularFeaturedApps(ctx, cfg, opts...)
-	if err != nil {
-		log.Debug("errored getting popular featured apps")
-		errChan <- err
-		return
-	}
-	log.Debug("got popular featured apps")
-	errChan <- nil
-	appsChan <- apps
-	log.Debug("done getting popular featured apps")
+	d.popularFeaturedAppsQuery(cfg).RunAsync(ctx, appsChan, errChan, opts...)
 }
 
+// PublicAppsQuery returns the public apps, most recently integrated first.
 func (d *Database) PublicAppsQuery(ctx context.Context, username string, groupIndex int, publicAppIDs []string, opts ...QueryOption) ([]App, error) {
 	ctx, span := otel.Tracer(otelName).Start(ctx, "PublicAppsQuery")
 	defer span.End()
 
-	var (
-		err  error
-		db   GoquDatabase
-		apps []App
-	)
-
-	querySettings := &QuerySettings{}
-	for _, opt := range opts {
-		opt(querySettings)
-	}
-
-	if querySettings.tx != nil {
-		db = querySettings.tx
-	} else {
-		db = d.goquDB
-	}
-
-	a := goqu.T("app_listing")
-	w := goqu.T("workspace")
-	acg := goqu.T("app_category_group")
-	aca := goqu.T("app_category_app")
-	u := goqu.T("users")
-
-	subquery := db.From(u).
-		Join(w, goqu.On(u.Col("id").Eq(w.Col("user_id")))).
-		Join(acg, goqu.On(w.Col("root_category_id").Eq(acg.Col("parent_category_id")))).
-		Join(aca, goqu.On(acg.Col("child_category_id").Eq(aca.Col("app_category_id")))).
-		Where(
-			u.Col("username").Eq(username),
-			acg.Col("child_index").Eq(groupIndex),
-			aca.Col("app_id").Eq(a.Col("id")),
-		)
-
-	query := db.From(a).
-		Select(
-			a.Col("id"),
-			goqu.L(`'de'`).As(goqu.C("system_id")),
-			a.Col("name"),
-			a.Col("description"),
-			a.Col("wiki_url"),
-			a.Col("integration_date"),
-			a.Col("edited_date"),
-			a.Col("integrator_username").As(goqu.C("username")),
-			goqu.L("EXISTS(?)", subquery).As(goqu.C("is_favorite")),
-			goqu.L("true").As(goqu.C("is_public")),
-		).
-		Where(
-			a.Col("id").Eq(goqu.Any(pq.Array(publicAppIDs))),
-			a.Col("deleted").Eq(goqu.L("false")),
-			a.Col("disabled").Eq(goqu.L("false")),
-			a.Col("integration_date").IsNotNull(),
-		).
-		Order(
-			a.Col("integration_date").Desc(),
-		)
-
-	if querySettings.hasLimit {
-		query = query.Limit(querySettings.limit)
-	}
-
-	if querySettings.hasOffset {
-		query = query.Offset(querySettings.offset)
-	}
-
-	executor := query.Executor()
-
-	apps = make([]App, 0)
-	if err = executor.ScanStructsContext(ctx, &apps); err != nil {
-		return nil, err
-	}
+	return d.publicAppsQuery(username, groupIndex, publicAppIDs).Run(ctx, opts...)
+}
 
-	return apps, nil
+func (d *Database) publicAppsQuery(username string, groupIndex int, publicAppIDs []string) *AppQuery {
+	return d.NewAppQuery("public apps", username, groupIndex).
+		PublicOnly(publicAppIDs).
+		IntegratedOnly().
+		RankBy(RankByIntegrationDate)
 }
 
 func (d *Database) PublicAppsQueryAsync(ctx context.Context, appsChan chan []App, errChan chan error, username string, groupIndex int, publicAppIDs []string, opts ...QueryOption) {
-	log.Debug("getting public apps")
-	apps, err := d.PublicAppsQuery(ctx, username, groupIndex, publicAppIDs, opts...)
-	if err != nil {
-		log.Debug("errored getting public apps")
-		errChan <- err
-		return
-	}
-	log.Debug("got public apps")
-	errChan <- nil
-	appsChan <- apps
-	log.Debug("done getting public apps")
+	d.publicAppsQuery(username, groupIndex, publicAppIDs).RunAsync(ctx, appsChan, errChan, opts...)
 }
 
+// RecentlyAddedApps returns the apps integrated by the user, most recently
+// integrated first.
 func (d *Database) RecentlyAddedApps(ctx context.Context, username string, groupIndex int, publicAppIDS []string, opts ...QueryOption) ([]App, error) {
 	ctx, span := otel.Tracer(otelName).Start(ctx, "RecentlyAddedApps")
 	defer span.End()
 
-	var (
-		err  error
-		db   GoquDatabase
-		apps []App
-	)
-
-	querySettings := &QuerySettings{}
-	for _, opt := range opts {
-		opt(querySettings)
-	}
-
-	if querySettings.tx != nil {
-		db = querySettings.tx
-	} else {
-		db = d.goquDB
-	}
-
-	a := goqu.T("app_listing")
-	w := goqu.T("workspace")
-	acg := goqu.T("app_category_group")
-	aca := goqu.T("app_category_app")
-	u := goqu.T("users")
-
-	subquery := db.From(u).
-		Join(w, goqu.On(u.Col("id").Eq(w.Col("user_id")))).
-		Join(acg, goqu.On(w.Col("root_category_id").Eq(acg.Col("parent_category_id")))).
-		Join(aca, goqu.On(acg.Col("child_category_id").Eq(aca.Col("app_category_id")))).
-		Where(
-			u.Col("username").Eq(username),
-			acg.Col("child_index").Eq(groupIndex),
-			aca.Col("app_id").Eq(a.Col("id")),
-		)
-
-	query := db.From(a).
-		Select(
-			a.Col("id"),
-			goqu.L(`'de'`).As(goqu.C("system_id")),
-			a.Col("name"),
-			a.Col("description"),
-			a.Col("wiki_url"),
-			a.Col("integration_date"),
-			a.Col("edited_date"),
-			a.Col("integrator_username").As(goqu.C("username")),
-			goqu.L("EXISTS(?)", subquery).As(goqu.C("is_favorite")),
-			a.Col("id").Eq(goqu.Any(pq.Array(publicAppIDS))).As(goqu.C("is_public")),
-		).
-		Where(
-			a.Col("deleted").Eq(goqu.L("false")),
-			a.Col("disabled").Eq(goqu.L("false")),
-			a.Col("integrator_username").Eq(username),
-		).
-		Order(
-			a.Col("integration_date").Desc(),
-		)
-
-	if querySettings.hasLimit {
-		query = query.Limit(querySettings.limit)
-	}
-
-	if querySettings.hasOffset {
-		query = query.Offset(querySettings.offset)
-	}
-
-	log.Debug("done generating query for recently added apps")
-
-	executor := query.Executor()
-
-	apps = make([]App, 0)
-	if err = executor.ScanStructsContext(ctx, &apps); err != nil {
-		return nil, err
-	}
-
-	log.Debug("done running/scanning query for recently added apps")
+	return d.recentlyAddedAppsQuery(username, groupIndex, publicAppIDS).Run(ctx, opts...)
+}
 
-	return apps, nil
+func (d *Database) recentlyAddedAppsQuery(username string, groupIndex int, publicAppIDS []string) *AppQuery {
+	return d.NewAppQuery("recently added apps", username, groupIndex).
+		WithPublicAppIDs(publicAppIDS).
+		IntegratedBy(username).
+		RankBy(RankByIntegrationDate)
 }
 
 func (d *Database) RecentlyAddedAppsAsync(ctx context.Context, appsChan chan []App, errChan chan error, username string, groupIndex int, publicAppIDS []string, opts ...QueryOption) {
-	log.Debug("getting recently added apps")
-	apps, err := d.RecentlyAddedApps(ctx, username, groupIndex, publicAppIDS, opts...)
-	if err != nil {
-		log.Debug("error getting recently added apps")
-		errChan <- err
-		return
-	}
-	log.Debug("got recently added apps")
-	errChan <- nil
-	appsChan <- apps
-	log.Debug("done getting recently added apps")
+	d.recentlyAddedAppsQuery(username, groupIndex, publicAppIDS).RunAsync(ctx, appsChan, errChan, opts...)
 }
 
+// RecentlyUsedApps returns the apps the user ran during cfg.StartDateInterval,
+// most recently run first.
 func (d *Database) RecentlyUsedApps(ctx context.Context, cfg *AppsQueryConfig, opts ...QueryOption) ([]App, error) {
 	ctx, span := otel.Tracer(otelName).Start(ctx, "RecentlyUsedApps")
 	defer span.End()
 
-	var (
-		err  error
-		db   GoquDatabase
-		apps []App
-	)
+	return d.recentlyUsedAppsQuery(cfg).Run(ctx, opts...)
+}
 
-	querySettings := &QuerySettings{}
-	for _, opt := range opts {
-		opt(querySettings)
-	}
+func (d *Database) recentlyUsedAppsQuery(cfg *AppsQueryConfig) *AppQuery {
+	return d.NewAppQuery("recently used apps", cfg.Username, cfg.GroupsIndex).
+		WithPublicAppIDs(cfg.AppIDs).
+		WithJobs(JobsFilter{Username: cfg.Username, Since: cfg.StartDateInterval, Required: true}).
+		RankBy(RankByMostRecentUse)
+}
 
-	if querySettings.tx != nil {
-		db = querySettings.tx
-	} else {
-		db = d.goquDB
-	}
+func (d *Database) RecentlyUsedAppsAsync(ctx context.Context, appsChan chan []App, errChan chan error, cfg *AppsQueryConfig, opts ...QueryOption) {
+	d.recentlyUsedAppsQuery(cfg).RunAsync(ctx, appsChan, errChan, opts...)
+}
 
-	a := goqu.T("app_listing")
-	j := goqu.T("jobs")
-	w := goqu.T("workspace")
-	acg := goqu.T("app_category_group")
-	aca := goqu.T("app_category_app")
-	u := goqu.T("users")
-
-	subquery := db.From(u).
-		Join(w, goqu.On(u.Col("id").Eq(w.Col("user_id")))).
-		Join(acg, goqu.On(w.Col("root_category_id").Eq(acg.Col("parent_category_id")))).
-		Join(aca, goqu.On(acg.Col("child_category_id").Eq(aca.Col("app_category_id")))).
-		Where(
-			u.Col("username").Eq(cfg.Username),
-			acg.Col("child_index").Eq(cfg.GroupsIndex),
-			aca.Col("app_id").Eq(a.Col("id")),
-		)
-
-	query := db.From(j).
-		Select(
-			a.Col("id"),
-			goqu.L(`'de'`).As(goqu.C("system_id")),
-			a.Col("name"),
-			a.Col("description"),
-			a.Col("wiki_url"),
-			a.Col("integration_date"),
-			a.Col("edited_date"),
-			a.Col("integrator_username").As(goqu.C("username")),
-			goqu.L("EXISTS(?)", subquery).As(goqu.C("is_favorite")),
-			a.Col("id").Eq(goqu.Any(pq.Array(cfg.AppIDs))).As(goqu.C("is_public")),
-			goqu.MAX(j.Col("start_date")).As(goqu.C("most_recent_start_date")),
-		).
-		Join(u, goqu.On(j.Col("user_id").Eq(u.Col("id")))).
-		Join(a, goqu.On(goqu.Cast(a.Col("id"), "TEXT").Eq(j.Col("app_id")))).
-		Where(
-			u.Col("username").Eq(cfg.Username),
-			a.Col("deleted").IsFalse(),
-			a.Col("disabled").IsFalse(),
-			j.Col("start_date").Gt(goqu.L("now() - ?", goqu.Cast(goqu.L(fmt.Sprintf("'%s'", cfg.StartDateInterval)), "INTERVAL"))),
-		).
-		GroupBy(
-			a.Col("id"),
-			a.Col("name"),
-			a.Col("description"),
-			a.Col("wiki_url"),
-			a.Col("integration_date"),
-			a.Col("edited_date"),
-			a.Col("integrator_username"),
-		).
-		Order(
-			goqu.C("most_recent_start_date").Desc(),
-		)
-
-	if querySettings.hasLimit {
-		query = query.Limit(querySettings.limit)
-	}
+// FavoriteApps returns the user's favorite apps, most recently run first.
+func (d *Database) FavoriteApps(ctx context.Context, cfg *AppsQueryConfig, opts ...QueryOption) ([]App, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "FavoriteApps")
+	defer span.End()
 
-	if querySettings.hasOffset {
-		query = query.Offset(querySettings.offset)
-	}
+	return d.favoriteAppsQuery(cfg).Run(ctx, opts...)
+}
 
-	log.Debug("done generating query for recently used apps")
+func (d *Database) favoriteAppsQuery(cfg *AppsQueryConfig) *AppQuery {
+	return d.NewAppQuery("favorite apps", cfg.Username, cfg.GroupsIndex).
+		WithPublicAppIDs(cfg.AppIDs).
+		FavoritesOnly().
+		WithJobs(JobsFilter{Username: cfg.Username}).
+		RankBy(RankByMostRecentUse)
+}
 
-	executor := query.Executor()
+func (d *Database) FavoriteAppsAsync(ctx context.Context, appsChan chan []App, errChan chan error, cfg *AppsQueryConfig, opts ...QueryOption) {
+	d.favoriteAppsQuery(cfg).RunAsync(ctx, appsChan, errChan, opts...)
+}
 
-	apps = make([]App, 0)
-	if err = executor.ScanStructsContext(ctx, &apps); err != nil {
-		return nil, err
-	}
+// SharedApps returns the apps in sharedAppIDs, most recently changed first.
+func (d *Database) SharedApps(ctx context.Context, cfg *AppsQueryConfig, sharedAppIDs []string, opts ...QueryOption) ([]App, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "SharedApps")
+	defer span.End()
 
-	log.Debug("done running/scanning query for recently used apps")
+	return d.sharedAppsQuery(cfg, sharedAppIDs).Run(ctx, opts...)
+}
 
-	return apps, nil
+func (d *Database) sharedAppsQuery(cfg *AppsQueryConfig, sharedAppIDs []string) *AppQuery {
+	return d.NewAppQuery("shared apps", cfg.Username, cfg.GroupsIndex).
+		WithPublicAppIDs(cfg.AppIDs).
+		WithAppIDs(sharedAppIDs).
+		RankBy(RankByLastChange)
 }
 
-func (d *Database) RecentlyUsedAppsAsync(ctx context.Context, appsChan chan []App, errChan chan error, cfg *AppsQueryConfig, opts ...QueryOption) {
-	log.Debug("getting recently used apps")
-	apps, err := d.RecentlyUsedApps(ctx, cfg, opts...)
-	if err != nil {
-		log.Debug("errored getting recently used apps")
-		errChan <- err
-		return
-	}
-	log.Debug("got recently used apps")
-	errChan <- nil
-	appsChan <- apps
-	log.Debug("done getting recently used apps")
+func (d *Database) SharedAppsAsync(ctx context.Context, appsChan chan []App, errChan chan error, cfg *AppsQueryConfig, sharedAppIDs []string, opts ...QueryOption) {
+	d.sharedAppsQuery(cfg, sharedAppIDs).RunAsync(ctx, appsChan, errChan, opts...)
+}
+
+// RecentlyRunApps returns the public apps most recently run by anyone. It
+// doesn't look up favorites, since it isn't specific to a user.
+func (d *Database) RecentlyRunApps(ctx context.Context, cfg *AppsQueryConfig, opts ...QueryOption) ([]App, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "RecentlyRunApps")
+	defer span.End()
+
+	return d.NewAppQuery("recently run apps", "", cfg.GroupsIndex).
+		PublicOnly(cfg.AppIDs).
+		WithJobs(JobsFilter{Since: cfg.StartDateInterval, Required: true}).
+		RankBy(RankByMostRecentUse).
+		Run(ctx, opts...)
+}
+
+// AppsByID returns the apps with the given IDs, most recently integrated
+// first. The cfg.AppIDs field contains the public app IDs.
+func (d *Database) AppsByID(ctx context.Context, cfg *AppsQueryConfig, appIDs []string, opts ...QueryOption) ([]App, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "AppsByID")
+	defer span.End()
+
+	return d.NewAppQuery("apps by id", cfg.Username, cfg.GroupsIndex).
+		WithPublicAppIDs(cfg.AppIDs).
+		WithAppIDs(appIDs).
+		RankBy(RankByIntegrationDate).
+		Run(ctx, opts...)
 }
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// publicCategoriesQuery returns a subquery over the public categories the app
+// is in. Categories are public if they belong to a public workspace.
+func publicCategoriesQuery(db GoquDatabase, a exp.IdentifierExpression) *goqu.SelectDataset {
+	aca := goqu.T("app_category_app")
+	ac := goqu.T("app_categories")
+	w := goqu.T("workspace")
+
+	return db.From(aca).
+		Join(ac, goqu.On(aca.Col("app_category_id").Eq(ac.Col("id")))).
+		Join(w, goqu.On(ac.Col("workspace_id").Eq(w.Col("id")))).
+		Where(
+			aca.Col("app_id").Eq(a.Col("id")),
+			w.Col("is_public").IsTrue(),
+		)
+}
+
+// publicCategoryNamesQuery returns a subquery that lists the names of the
+// public categories the app is in as a single string.
+func publicCategoryNamesQuery(db GoquDatabase, a exp.IdentifierExpression) *goqu.SelectDataset {
+	ac := goqu.T("app_categories")
+	return publicCategoriesQuery(db, a).Select(goqu.L("string_agg(?, ' ')", ac.Col("name")))
+}
+
+// categoriesColumn returns the categories column for an app query, which
+// contains the sorted names of the public categories the app is in.
+func categoriesColumn(db GoquDatabase, a exp.IdentifierExpression) interface{} {
+	ac := goqu.T("app_categories")
+	names := publicCategoriesQuery(db, a).
+		Select(ac.Col("name")).
+		Distinct().
+		Order(ac.Col("name").Asc())
+	return goqu.L("ARRAY(?)", names).As(goqu.C("categories"))
+}
+
+// inCategoryCondition returns a condition that matches apps in the public
+// category with the given name, ignoring case.
+func inCategoryCondition(db GoquDatabase, a exp.IdentifierExpression, category string) exp.Expression {
+	ac := goqu.T("app_categories")
+	query := publicCategoriesQuery(db, a).
+		Select(goqu.L("1")).
+		Where(goqu.L("lower(?)", ac.Col("name")).Eq(strings.ToLower(category)))
+	return goqu.L("EXISTS(?)", query)
+}
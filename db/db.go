@@ -61,11 +61,14 @@ func New(dbconn *sqlx.DB) *Database {
 }
 
 type QuerySettings struct {
-	hasLimit  bool
-	limit     uint
-	hasOffset bool
-	offset    uint
-	tx        *goqu.TxDatabase
+	hasLimit    bool
+	limit       uint
+	hasOffset   bool
+	offset      uint
+	tx          *goqu.TxDatabase
+	expand      map[Expansion]bool
+	category    string
+	hierarchies HierarchyLookup
 }
 
 // QueryOption defines the signature for functions that can modify a QuerySettings
@@ -95,6 +98,27 @@ func WithTX(tx *goqu.TxDatabase) QueryOption {
 	}
 }
 
+// WithExpansions allows callers to include optional information in the apps
+// returned by a query.
+func WithExpansions(expansions ...Expansion) QueryOption {
+	return func(s *QuerySettings) {
+		if s.expand == nil {
+			s.expand = make(map[Expansion]bool)
+		}
+		for _, e := range expansions {
+			s.expand[e] = true
+		}
+	}
+}
+
+// WithCategory allows callers to limit the apps returned by a query to those
+// in the public category with the given name.
+func WithCategory(category string) QueryOption {
+	return func(s *QuerySettings) {
+		s.category = category
+	}
+}
+
 func Connect(config *config.DatabaseConfiguration) (*sqlx.DB, error) {
 	dbURI := fmt.Sprintf(
 		"postgresql://%s:%s@%s:%d/%s?sslmode=disable",
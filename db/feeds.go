@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.opentelemetry.io/otel"
+)
+
+// feedItemsTable stores the last good items of each public feed.
+const feedItemsTable = "dashboard_feed_items"
+
+// FeedItemsTableExists returns true if the table used to store feed items
+// exists.
+func (d *Database) FeedItemsTableExists(ctx context.Context) (bool, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "FeedItemsTableExists")
+	defer span.End()
+
+	var exists bool
+	if _, err := d.goquDB.Select(goqu.L("to_regclass(?) IS NOT NULL", feedItemsTable)).
+		Executor().
+		ScanValContext(ctx, &exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// CreateFeedItemsTable creates the table used to store feed items if it
+// doesn't already exist. It's only called when feeds.cache.create_table is
+// set; otherwise the table is expected to be created along with the rest of
+// the DE schema.
+func (d *Database) CreateFeedItemsTable(ctx context.Context) error {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "CreateFeedItemsTable")
+	defer span.End()
+
+	_, err := d.goquDB.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS `+feedItemsTable+` (
+			name text PRIMARY KEY,
+			items jsonb NOT NULL,
+			updated_at timestamp with time zone NOT NULL DEFAULT now()
+		)`)
+	return err
+}
+
+type storedFeedItems struct {
+	Name  string `db:"name"`
+	Items []byte `db:"items"`
+}
+
+// FeedItems returns the stored items of each feed, keyed by feed name. It
+// implements feeds.ItemStore.
+func (d *Database) FeedItems(ctx context.Context) (map[string]json.RawMessage, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "FeedItems")
+	defer span.End()
+
+	t := goqu.T(feedItemsTable)
+
+	var rows []storedFeedItems
+	if err := d.goquDB.From(t).
+		Select(t.Col("name"), t.Col("items")).
+		Executor().
+		ScanStructsContext(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]json.RawMessage, len(rows))
+	for _, row := range rows {
+		stored[row.Name] = row.Items
+	}
+
+	return stored, nil
+}
+
+// SaveFeedItems replaces the stored items of the named feed. It implements
+// feeds.ItemStore.
+func (d *Database) SaveFeedItems(ctx context.Context, name string, items json.RawMessage) error {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "SaveFeedItems")
+	defer span.End()
+
+	t := goqu.T(feedItemsTable)
+
+	_, err := d.goquDB.Insert(t).
+		Rows(goqu.Record{
+			"name":  name,
+			"items": goqu.Cast(goqu.V(string(items)), "jsonb"),
+		}).
+		OnConflict(goqu.DoUpdate("name", goqu.Record{
+			"items":      goqu.L("EXCLUDED.items"),
+			"updated_at": goqu.L("now()"),
+		})).
+		Executor().
+		ExecContext(ctx)
+	return err
+}
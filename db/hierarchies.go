@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+	"go.opentelemetry.io/otel"
+)
+
+// HierarchyClass is a class in an ontology hierarchy that an app is
+// classified under. Only the branches leading to the app's own classes are
+// included in the subclasses.
+type HierarchyClass struct {
+	IRI        string           `json:"iri"`
+	Label      string           `json:"label"`
+	Subclasses []HierarchyClass `json:"subclasses,omitempty"`
+}
+
+// HierarchyLookup returns the ontology hierarchies each app is classified
+// under, keyed by app ID. The username is the user the query is run for, and
+// is empty for anonymous queries. The hierarchies aren't stored in the apps
+// database, so the lookup is provided by the caller.
+type HierarchyLookup func(ctx context.Context, username string, appIDs []string) (map[string][]HierarchyClass, error)
+
+// WithHierarchyLookup sets the lookup used to fill in the hierarchies of the
+// apps returned by a query when the ExpandHierarchies expansion is requested.
+func WithHierarchyLookup(lookup HierarchyLookup) QueryOption {
+	return func(s *QuerySettings) {
+		s.hierarchies = lookup
+	}
+}
+
+// HierarchyVersion returns the version of the ontology that was most recently
+// applied to the app hierarchies, or an empty string if none has been.
+func (d *Database) HierarchyVersion(ctx context.Context) (string, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "HierarchyVersion")
+	defer span.End()
+
+	v := goqu.T("app_hierarchy_version")
+	query := d.goquDB.From(v).
+		Select(v.Col("version")).
+		Order(v.Col("applied").Desc()).
+		Limit(1)
+
+	var version string
+	if _, err := query.Executor().ScanValContext(ctx, &version); err != nil {
+		return "", err
+	}
+
+	return version, nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"go.opentelemetry.io/otel"
+)
+
+// Values of the change_type column returned by NewApps.
+const (
+	// ChangeTypeNew marks apps integrated within the window.
+	ChangeTypeNew = "new"
+
+	// ChangeTypeUpdated marks apps integrated before the window but
+	// significantly edited within it.
+	ChangeTypeUpdated = "updated"
+)
+
+// changedSince limits the apps to those integrated or significantly edited
+// within the interval and adds the change_type column. Edits only count as
+// significant if they were made at least editThreshold after the app was
+// integrated, so that the fixes made right after an app is published don't
+// list it as updated.
+func changedSince(a exp.IdentifierExpression, query *goqu.SelectDataset, interval, editThreshold string) *goqu.SelectDataset {
+	since := intervalAgo(interval)
+	significantEdit := goqu.L("? + ?", a.Col("integration_date"), goqu.Cast(goqu.V(editThreshold), "INTERVAL"))
+
+	changeType := goqu.Case().
+		When(a.Col("integration_date").Gte(since), ChangeTypeNew).
+		Else(ChangeTypeUpdated)
+
+	return query.
+		SelectAppend(changeType.As(goqu.C("change_type"))).
+		Where(goqu.Or(
+			a.Col("integration_date").Gte(since),
+			goqu.And(
+				a.Col("edited_date").Gte(since),
+				a.Col("edited_date").Gte(significantEdit),
+			),
+		))
+}
+
+// NewApps returns the public apps that were integrated or significantly edited
+// during cfg.StartDateInterval, most recently changed first. Edits are
+// significant if they were made at least editThreshold after the app was
+// integrated. Each app's change_type says whether it's new or updated.
+func (d *Database) NewApps(ctx context.Context, cfg *AppsQueryConfig, editThreshold string, opts ...QueryOption) ([]App, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "NewApps")
+	defer span.End()
+
+	return d.newAppsQuery(cfg, editThreshold).Run(ctx, opts...)
+}
+
+func (d *Database) newAppsQuery(cfg *AppsQueryConfig, editThreshold string) *AppQuery {
+	return d.NewAppQuery("new apps", cfg.Username, cfg.GroupsIndex).
+		PublicOnly(cfg.AppIDs).
+		IntegratedOnly().
+		ChangedSince(cfg.StartDateInterval, editThreshold).
+		RankBy(RankByLastChange)
+}
+
+func (d *Database) NewAppsAsync(ctx context.Context, appsChan chan []App, errChan chan error, cfg *AppsQueryConfig, editThreshold string, opts ...QueryOption) {
+	d.newAppsQuery(cfg, editThreshold).RunAsync(ctx, appsChan, errChan, opts...)
+}
@@ -0,0 +1,524 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/lib/pq"
+)
+
+// AppRanking determines the order of the apps returned by an AppQuery.
+type AppRanking int
+
+const (
+	// RankByIntegrationDate lists the most recently integrated apps first.
+	RankByIntegrationDate AppRanking = iota
+
+	// RankByJobCount lists the apps with the most jobs first. Requires jobs.
+	RankByJobCount
+
+	// RankByDistinctUsers lists the apps run by the most users first.
+	// Requires jobs.
+	RankByDistinctUsers
+
+	// RankByPopularityScore lists apps by the number of distinct users plus
+	// the log of the number of jobs. Requires jobs.
+	RankByPopularityScore
+
+	// RankByMostRecentUse lists the most recently run apps first, followed by
+	// the apps that were never run in alphabetical order. Requires jobs.
+	RankByMostRecentUse
+
+	// RankByLastChange lists the most recently edited or integrated apps
+	// first.
+	RankByLastChange
+
+	// RankByAppIDOrder lists the apps in the order of the IDs passed to
+	// WithAppIDs.
+	RankByAppIDOrder
+
+	// RankByRating lists the apps with the highest average rating first,
+	// breaking ties by the number of ratings. Unrated apps come last.
+	RankByRating
+
+	// RankByGrowth lists the apps whose usage grew the most between the job
+	// windows first: by the growth in distinct users so that one busy user
+	// can't push an app up the list, then by the growth in jobs. Remaining
+	// ties are broken by name and ID so that paging is stable. Requires
+	// WithJobWindows.
+	RankByGrowth
+
+	// RankBySearchRank lists the best matches for the search text first.
+	// Requires MatchingSearch.
+	RankBySearchRank
+)
+
+// Ranking returns the AppRanking for the popularity sort.
+func (p PopularitySort) Ranking() AppRanking {
+	switch p {
+	case SortByUsers:
+		return RankByDistinctUsers
+	case SortByScore:
+		return RankByPopularityScore
+	default:
+		return RankByJobCount
+	}
+}
+
+// Expansion names optional information that can be included in the apps
+// returned by an AppQuery.
+type Expansion string
+
+const (
+	// ExpandCategories includes the names of the public categories each app
+	// is in.
+	ExpandCategories Expansion = "categories"
+
+	// ExpandTools includes the tools and container images used by each app.
+	ExpandTools Expansion = "tools"
+
+	// ExpandHierarchies includes the ontology hierarchies each app is
+	// classified under. They come from the lookup set with
+	// WithHierarchyLookup, and are left out if there isn't one.
+	ExpandHierarchies Expansion = "hierarchies"
+)
+
+// ParseExpansion returns the Expansion with the given name.
+func ParseExpansion(s string) (Expansion, error) {
+	switch e := Expansion(s); e {
+	case ExpandCategories, ExpandTools, ExpandHierarchies:
+		return e, nil
+	default:
+		return "", fmt.Errorf("unknown expansion %q; must be %s, %s, or %s", s, ExpandCategories, ExpandTools, ExpandHierarchies)
+	}
+}
+
+// JobsFilter determines which jobs are joined to the apps in an AppQuery. The
+// job_count, distinct_users, and most_recent_start_date columns are computed
+// from the matching jobs.
+type JobsFilter struct {
+	// Username limits the jobs to those run by the user.
+	Username string
+
+	// Since is a Postgres interval that limits the jobs to those started
+	// within it.
+	Since string
+
+	// Required leaves out apps without any matching jobs.
+	Required bool
+}
+
+// AppQuery builds a query listing apps in the shape of the App type. Filters,
+// optional columns, and the ranking are added with its methods, then the query
+// is run with Run.
+type AppQuery struct {
+	d              *Database
+	name           string
+	username       string
+	groupIndex     int
+	publicAppIDs   []string
+	publicOnly     bool
+	appIDs         []string
+	filterAppIDs   bool
+	accessible     bool
+	sharedAppIDs   []string
+	integrator     string
+	integratedOnly bool
+	changedSince   string
+	editThreshold  string
+	favoritesOnly  bool
+	minRatings     int
+	jobs           *JobsFilter
+	jobWindow      string
+	search         *AppSearch
+	ranking        AppRanking
+}
+
+// NewAppQuery returns a new *AppQuery. The name is used in log messages. The
+// username and group index are used to look up the user's favorite apps; the
+// favorites lookup is skipped if the username is empty.
+func (d *Database) NewAppQuery(name, username string, groupIndex int) *AppQuery {
+	return &AppQuery{
+		d:          d,
+		name:       name,
+		username:   username,
+		groupIndex: groupIndex,
+	}
+}
+
+// WithAppIDs limits the apps to those with the given IDs.
+func (q *AppQuery) WithAppIDs(appIDs []string) *AppQuery {
+	q.appIDs = appIDs
+	q.filterAppIDs = true
+	return q
+}
+
+// WithPublicAppIDs sets the IDs used to compute the is_public column.
+func (q *AppQuery) WithPublicAppIDs(publicAppIDs []string) *AppQuery {
+	q.publicAppIDs = publicAppIDs
+	return q
+}
+
+// PublicOnly limits the apps to the public apps with the given IDs.
+func (q *AppQuery) PublicOnly(publicAppIDs []string) *AppQuery {
+	q.publicAppIDs = publicAppIDs
+	q.publicOnly = true
+	return q
+}
+
+// IntegratedBy limits the apps to those integrated by the user.
+func (q *AppQuery) IntegratedBy(username string) *AppQuery {
+	q.integrator = username
+	return q
+}
+
+// IntegratedOnly leaves out apps without an integration date.
+func (q *AppQuery) IntegratedOnly() *AppQuery {
+	q.integratedOnly = true
+	return q
+}
+
+// AccessibleTo limits the apps to the public apps set with WithPublicAppIDs,
+// the apps shared with the query's user, and the user's own apps.
+func (q *AppQuery) AccessibleTo(sharedAppIDs []string) *AppQuery {
+	q.accessible = true
+	q.sharedAppIDs = sharedAppIDs
+	return q
+}
+
+// ChangedSince limits the apps to those integrated or edited within the
+// interval and adds the change_type column, which says which of the two
+// happened. Edits made less than editThreshold after the app was integrated
+// are ignored.
+func (q *AppQuery) ChangedSince(interval, editThreshold string) *AppQuery {
+	q.changedSince = interval
+	q.editThreshold = editThreshold
+	return q
+}
+
+// FavoritesOnly limits the apps to the user's favorites.
+func (q *AppQuery) FavoritesOnly() *AppQuery {
+	q.favoritesOnly = true
+	return q
+}
+
+// MinRatings leaves out apps with fewer than n ratings.
+func (q *AppQuery) MinRatings(n int) *AppQuery {
+	q.minRatings = n
+	return q
+}
+
+// WithJobs joins the jobs matching the filter to the apps.
+func (q *AppQuery) WithJobs(filter JobsFilter) *AppQuery {
+	q.jobs = &filter
+	return q
+}
+
+// WithJobWindows compares each app's jobs in the most recent interval to its
+// jobs in the interval before that, leaving out apps without any recent jobs.
+// The job_count and distinct_users columns cover the recent window. May not be
+// combined with WithJobs.
+func (q *AppQuery) WithJobWindows(interval string) *AppQuery {
+	q.jobWindow = interval
+	return q
+}
+
+// MatchingSearch limits the apps to those matching the search text and adds
+// the search_rank column.
+func (q *AppQuery) MatchingSearch(search *AppSearch) *AppQuery {
+	q.search = search
+	return q
+}
+
+// RankBy sets the order of the apps.
+func (q *AppQuery) RankBy(ranking AppRanking) *AppQuery {
+	q.ranking = ranking
+	return q
+}
+
+// intervalAgo returns an expression for the time the interval before now.
+func intervalAgo(interval string) exp.LiteralExpression {
+	return goqu.L("now() - ?", goqu.Cast(goqu.V(interval), "INTERVAL"))
+}
+
+// favoritesQuery returns a subquery that finds the app in the user's
+// favorites.
+func favoritesQuery(db GoquDatabase, a exp.IdentifierExpression, username string, groupIndex int) *goqu.SelectDataset {
+	u := goqu.T("users")
+	w := goqu.T("workspace")
+	acg := goqu.T("app_category_group")
+	aca := goqu.T("app_category_app")
+
+	return db.From(u).
+		Join(w, goqu.On(u.Col("id").Eq(w.Col("user_id")))).
+		Join(acg, goqu.On(w.Col("root_category_id").Eq(acg.Col("parent_category_id")))).
+		Join(aca, goqu.On(acg.Col("child_category_id").Eq(aca.Col("app_category_id")))).
+		Where(
+			u.Col("username").Eq(username),
+			acg.Col("child_index").Eq(groupIndex),
+			aca.Col("app_id").Eq(a.Col("id")),
+		)
+}
+
+// isFavoriteColumn returns the is_favorite column for an app query. The
+// favorites lookup is skipped for anonymous queries, which have no username.
+func isFavoriteColumn(db GoquDatabase, a exp.IdentifierExpression, username string, groupIndex int) interface{} {
+	if username == "" {
+		return goqu.L("false").As(goqu.C("is_favorite"))
+	}
+	return goqu.L("EXISTS(?)", favoritesQuery(db, a, username, groupIndex)).As(goqu.C("is_favorite"))
+}
+
+func (q *AppQuery) isPublicColumn(a exp.IdentifierExpression) interface{} {
+	switch {
+	case q.publicOnly:
+		return goqu.L("true").As(goqu.C("is_public"))
+	case q.publicAppIDs != nil:
+		return a.Col("id").Eq(goqu.Any(pq.Array(q.publicAppIDs))).As(goqu.C("is_public"))
+	default:
+		return goqu.L("false").As(goqu.C("is_public"))
+	}
+}
+
+func (q *AppQuery) joinJobs(db GoquDatabase, a exp.IdentifierExpression, query *goqu.SelectDataset) *goqu.SelectDataset {
+	j := goqu.T("jobs")
+	u := goqu.T("users")
+
+	conditions := []exp.Expression{
+		j.Col("app_id").Eq(goqu.Cast(a.Col("id"), "TEXT")),
+	}
+	if q.jobs.Since != "" {
+		conditions = append(conditions, j.Col("start_date").Gte(intervalAgo(q.jobs.Since)))
+	}
+	if q.jobs.Username != "" {
+		userQuery := db.From(u).Select(u.Col("id")).Where(u.Col("username").Eq(q.jobs.Username))
+		conditions = append(conditions, j.Col("user_id").Eq(userQuery))
+	}
+
+	query = query.SelectAppend(
+		goqu.COUNT(j.Col("id")).As(goqu.C("job_count")),
+		goqu.L("COUNT(DISTINCT ?)", j.Col("user_id")).As(goqu.C("distinct_users")),
+		goqu.MAX(j.Col("start_date")).As(goqu.C("most_recent_start_date")),
+	)
+
+	if q.jobs.Required {
+		query = query.Join(j, goqu.On(conditions...))
+	} else {
+		query = query.LeftJoin(j, goqu.On(conditions...))
+	}
+
+	return query.GroupBy(
+		a.Col("id"),
+		a.Col("name"),
+		a.Col("description"),
+		a.Col("wiki_url"),
+		a.Col("integration_date"),
+		a.Col("edited_date"),
+		a.Col("integrator_username"),
+	)
+}
+
+func (q *AppQuery) order(a exp.IdentifierExpression) []exp.OrderedExpression {
+	j := goqu.T("jobs")
+
+	switch q.ranking {
+	case RankByJobCount:
+		return []exp.OrderedExpression{goqu.C("job_count").Desc(), a.Col("name").Asc()}
+	case RankByDistinctUsers:
+		return []exp.OrderedExpression{goqu.C("distinct_users").Desc(), goqu.C("job_count").Desc(), a.Col("name").Asc()}
+	case RankByPopularityScore:
+		// Postgres doesn't allow column aliases inside of expressions in an
+		// ORDER BY clause.
+		score := goqu.L("COUNT(DISTINCT ?) + LN(1 + COUNT(?))", j.Col("user_id"), j.Col("id"))
+		return []exp.OrderedExpression{score.Desc(), goqu.C("distinct_users").Desc(), a.Col("name").Asc()}
+	case RankByMostRecentUse:
+		return []exp.OrderedExpression{goqu.C("most_recent_start_date").Desc().NullsLast(), a.Col("name").Asc()}
+	case RankByLastChange:
+		return []exp.OrderedExpression{
+			goqu.COALESCE(a.Col("edited_date"), a.Col("integration_date")).Desc().NullsLast(),
+			a.Col("name").Asc(),
+		}
+	case RankByRating:
+		return []exp.OrderedExpression{
+			goqu.C("average_rating").Desc().NullsLast(),
+			goqu.C("total_ratings").Desc(),
+			a.Col("name").Asc(),
+		}
+	case RankByGrowth:
+		return []exp.OrderedExpression{
+			goqu.C("growth_rate").Desc(),
+			growthRate("recent_job_count", "baseline_job_count").Desc(),
+			jobWindowsTable.Col("recent_users").Desc(),
+			a.Col("name").Asc(),
+			a.Col("id").Asc(),
+		}
+	case RankBySearchRank:
+		return []exp.OrderedExpression{goqu.C("search_rank").Desc(), a.Col("name").Asc()}
+	case RankByAppIDOrder:
+		position := goqu.L("array_position(?, ?)", goqu.Cast(goqu.V(pq.Array(q.appIDs)), "TEXT[]"), goqu.Cast(a.Col("id"), "TEXT"))
+		return []exp.OrderedExpression{position.Asc()}
+	default:
+		return []exp.OrderedExpression{a.Col("integration_date").Desc()}
+	}
+}
+
+// build returns the query described by the AppQuery and the query settings.
+func (q *AppQuery) build(db GoquDatabase, settings *QuerySettings) *goqu.SelectDataset {
+	a := goqu.T("app_listing")
+
+	query := db.From(a).
+		Select(
+			a.Col("id"),
+			goqu.L(`'de'`).As(goqu.C("system_id")),
+			a.Col("name"),
+			a.Col("description"),
+			a.Col("wiki_url"),
+			a.Col("integration_date"),
+			a.Col("edited_date"),
+			a.Col("integrator_username").As(goqu.C("username")),
+			isFavoriteColumn(db, a, q.username, q.groupIndex),
+			q.isPublicColumn(a),
+		).
+		SelectAppend(ratingColumns(db, a, q.username)...).
+		Where(
+			a.Col("deleted").IsFalse(),
+			a.Col("disabled").IsFalse(),
+		)
+
+	if q.filterAppIDs {
+		query = query.Where(a.Col("id").Eq(goqu.Any(pq.Array(q.appIDs))))
+	}
+
+	if q.publicOnly {
+		query = query.Where(a.Col("id").Eq(goqu.Any(pq.Array(q.publicAppIDs))))
+	}
+
+	if q.integrator != "" {
+		query = query.Where(a.Col("integrator_username").Eq(q.integrator))
+	}
+
+	if q.integratedOnly {
+		query = query.Where(a.Col("integration_date").IsNotNull())
+	}
+
+	if q.accessible {
+		query = query.Where(goqu.Or(
+			a.Col("id").Eq(goqu.Any(pq.Array(q.publicAppIDs))),
+			a.Col("id").Eq(goqu.Any(pq.Array(q.sharedAppIDs))),
+			a.Col("integrator_username").Eq(q.username),
+		))
+	}
+
+	if q.changedSince != "" {
+		query = changedSince(a, query, q.changedSince, q.editThreshold)
+	}
+
+	if q.favoritesOnly {
+		query = query.Where(goqu.L("EXISTS(?)", favoritesQuery(db, a, q.username, q.groupIndex)))
+	}
+
+	if settings.category != "" {
+		query = query.Where(inCategoryCondition(db, a, settings.category))
+	}
+
+	if settings.expand[ExpandCategories] {
+		query = query.SelectAppend(categoriesColumn(db, a))
+	}
+
+	if settings.expand[ExpandTools] {
+		query = query.SelectAppend(toolsColumn(db, a))
+	}
+
+	if q.minRatings > 0 {
+		query = query.Where(goqu.L("(?) >= ?", totalRatingsQuery(db, a), q.minRatings))
+	}
+
+	if q.search != nil {
+		query = q.search.apply(db, a, query)
+	}
+
+	if q.jobs != nil {
+		query = q.joinJobs(db, a, query)
+	}
+
+	if q.jobWindow != "" {
+		query = joinJobWindows(db, a, query, q.jobWindow)
+	}
+
+	return query.Order(q.order(a)...)
+}
+
+// Run runs the query and returns the apps.
+func (q *AppQuery) Run(ctx context.Context, opts ...QueryOption) ([]App, error) {
+	var (
+		err  error
+		db   GoquDatabase
+		apps []App
+	)
+
+	querySettings := &QuerySettings{}
+	for _, opt := range opts {
+		opt(querySettings)
+	}
+
+	if querySettings.tx != nil {
+		db = querySettings.tx
+	} else {
+		db = q.d.goquDB
+	}
+
+	query := q.build(db, querySettings)
+
+	if querySettings.hasLimit {
+		query = query.Limit(querySettings.limit)
+	}
+
+	if querySettings.hasOffset {
+		query = query.Offset(querySettings.offset)
+	}
+
+	log.Debugf("done generating query for %s", q.name)
+
+	executor := query.Executor()
+
+	apps = make([]App, 0)
+	if err = executor.ScanStructsContext(ctx, &apps); err != nil {
+		return nil, err
+	}
+
+	log.Debugf("done running/scanning query for %s", q.name)
+
+	if querySettings.expand[ExpandHierarchies] && querySettings.hierarchies != nil && len(apps) > 0 {
+		appIDs := make([]string, len(apps))
+		for i := range apps {
+			appIDs[i] = apps[i].ID
+		}
+		hierarchies, err := querySettings.hierarchies(ctx, q.username, appIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range apps {
+			apps[i].Hierarchies = hierarchies[apps[i].ID]
+		}
+	}
+
+	return apps, nil
+}
+
+// RunAsync runs the query, sending the error (or nil) on errChan followed by
+// the apps on appsChan if there wasn't an error.
+func (q *AppQuery) RunAsync(ctx context.Context, appsChan chan []App, errChan chan error, opts ...QueryOption) {
+	log.Debugf("getting %s", q.name)
+	apps, err := q.Run(ctx, opts...)
+	if err != nil {
+		log.Debugf("errored getting %s", q.name)
+		errChan <- err
+		return
+	}
+	log.Debugf("got %s", q.name)
+	errChan <- nil
+	appsChan <- apps
+	log.Debugf("done getting %s", q.name)
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"go.opentelemetry.io/otel"
+)
+
+// DefaultMinVotes is the default number of ratings an app needs before it's
+// included in the top-rated apps.
+const DefaultMinVotes = 5
+
+// totalRatingsQuery returns a subquery that counts the ratings for the app.
+func totalRatingsQuery(db GoquDatabase, a exp.IdentifierExpression) *goqu.SelectDataset {
+	r := goqu.T("ratings")
+	return db.From(r).
+		Select(goqu.COUNT(goqu.Star())).
+		Where(r.Col("app_id").Eq(a.Col("id")))
+}
+
+// ratingColumns returns the columns containing the average rating, the number
+// of ratings, and the requesting user's own rating for the app. The average is
+// null for apps that haven't been rated, so they aren't mistaken for apps rated
+// at zero stars, and the user's rating is always null for anonymous queries.
+func ratingColumns(db GoquDatabase, a exp.IdentifierExpression, username string) []interface{} {
+	r := goqu.T("ratings")
+	u := goqu.T("users")
+
+	averageQuery := db.From(r).
+		Select(goqu.AVG(r.Col("rating"))).
+		Where(r.Col("app_id").Eq(a.Col("id")))
+
+	userRating := goqu.L("NULL::integer").As(goqu.C("user_rating"))
+	if username != "" {
+		userQuery := db.From(r).
+			Select(r.Col("rating")).
+			Join(u, goqu.On(r.Col("user_id").Eq(u.Col("id")))).
+			Where(
+				r.Col("app_id").Eq(a.Col("id")),
+				u.Col("username").Eq(username),
+			)
+		userRating = goqu.L("(?)", userQuery).As(goqu.C("user_rating"))
+	}
+
+	return []interface{}{
+		goqu.L("(?)", averageQuery).As(goqu.C("average_rating")),
+		goqu.L("(?)", totalRatingsQuery(db, a)).As(goqu.C("total_ratings")),
+		userRating,
+	}
+}
+
+// TopRatedApps returns the public apps with the highest average ratings. Apps
+// with fewer than minVotes ratings are skipped.
+func (d *Database) TopRatedApps(ctx context.Context, cfg *AppsQueryConfig, minVotes int, opts ...QueryOption) ([]App, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "TopRatedApps")
+	defer span.End()
+
+	return d.NewAppQuery("top rated apps", cfg.Username, cfg.GroupsIndex).
+		PublicOnly(cfg.AppIDs).
+		IntegratedOnly().
+		MinRatings(minVotes).
+		RankBy(RankByRating).
+		Run(ctx, opts...)
+}
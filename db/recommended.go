@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/lib/pq"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel"
+)
+
+// Recommendation is an app recommended to a user, along with the number of
+// users with similar job histories that ran it.
+type Recommendation struct {
+	AppID   string `db:"id"`
+	CoUsers int64  `db:"co_users"`
+}
+
+// Recommendations returns up to limit public apps the user has never run that
+// were run by other users who ran the same apps as the user. Apps are ranked by
+// the number of those users that ran them. The cfg.StartDateInterval field is
+// how far back to look at jobs.
+//
+// This is the expensive part of finding recommended apps, so callers should
+// cache the results and pass them to RecommendedApps.
+func (d *Database) Recommendations(ctx context.Context, cfg *AppsQueryConfig, limit uint) ([]Recommendation, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "Recommendations")
+	defer span.End()
+
+	db := d.goquDB
+	a := goqu.T("app_listing")
+	j := goqu.T("jobs")
+	u := goqu.T("users")
+	userApps := goqu.T("user_apps")
+	peers := goqu.T("peers")
+	scores := goqu.T("scores")
+
+	lookbackStart := intervalAgo(cfg.StartDateInterval)
+
+	// The apps the user ran during the lookback window.
+	userAppsQuery := db.From(j).
+		Select(j.Col("app_id"), j.Col("user_id")).
+		Distinct().
+		Join(u, goqu.On(j.Col("user_id").Eq(u.Col("id")))).
+		Where(
+			u.Col("username").Eq(cfg.Username),
+			j.Col("start_date").Gte(lookbackStart),
+		)
+
+	// The other users who ran those apps during the lookback window.
+	peersQuery := db.From(j).
+		Select(j.Col("user_id")).
+		Distinct().
+		Join(userApps, goqu.On(j.Col("app_id").Eq(userApps.Col("app_id")))).
+		Where(
+			j.Col("user_id").Neq(userApps.Col("user_id")),
+			j.Col("start_date").Gte(lookbackStart),
+		)
+
+	// The number of those users that ran each app during the lookback window.
+	scoresQuery := db.From(j).
+		Select(
+			j.Col("app_id"),
+			goqu.L("COUNT(DISTINCT ?)", j.Col("user_id")).As(goqu.C("co_users")),
+		).
+		Join(peers, goqu.On(j.Col("user_id").Eq(peers.Col("user_id")))).
+		Where(j.Col("start_date").Gte(lookbackStart)).
+		GroupBy(j.Col("app_id"))
+
+	// Any job the user ran with the app, no matter how long ago.
+	ranQuery := db.From(j).
+		Select(goqu.L("1")).
+		Join(u, goqu.On(j.Col("user_id").Eq(u.Col("id")))).
+		Where(
+			u.Col("username").Eq(cfg.Username),
+			j.Col("app_id").Eq(goqu.Cast(a.Col("id"), "TEXT")),
+		)
+
+	query := db.From(a).
+		With("user_apps", userAppsQuery).
+		With("peers", peersQuery).
+		With("scores", scoresQuery).
+		Select(a.Col("id"), scores.Col("co_users")).
+		Join(scores, goqu.On(scores.Col("app_id").Eq(goqu.Cast(a.Col("id"), "TEXT")))).
+		Where(
+			a.Col("deleted").IsFalse(),
+			a.Col("disabled").IsFalse(),
+			a.Col("integration_date").IsNotNull(),
+			a.Col("id").Eq(goqu.Any(pq.Array(cfg.AppIDs))),
+			goqu.L("NOT EXISTS(?)", ranQuery),
+		).
+		Order(
+			scores.Col("co_users").Desc(),
+			a.Col("name").Asc(),
+		).
+		Limit(limit)
+
+	recommendations := make([]Recommendation, 0)
+	if err := query.Executor().ScanStructsContext(ctx, &recommendations); err != nil {
+		return nil, err
+	}
+
+	return recommendations, nil
+}
+
+// RecommendedApps returns the recommended apps in the order they were
+// recommended, with the co_users column filled in. The apps are limited to the
+// public apps in cfg.AppIDs, so recommendations for apps that have since been
+// made private are left out.
+func (d *Database) RecommendedApps(ctx context.Context, cfg *AppsQueryConfig, recommendations []Recommendation, opts ...QueryOption) ([]App, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "RecommendedApps")
+	defer span.End()
+
+	appIDs := lo.Map(recommendations, func(r Recommendation, _ int) string {
+		return r.AppID
+	})
+	coUsers := lo.SliceToMap(recommendations, func(r Recommendation) (string, int64) {
+		return r.AppID, r.CoUsers
+	})
+
+	apps, err := d.NewAppQuery("recommended apps", cfg.Username, cfg.GroupsIndex).
+		PublicOnly(cfg.AppIDs).
+		WithAppIDs(appIDs).
+		RankBy(RankByAppIDOrder).
+		Run(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range apps {
+		if n, ok := coUsers[apps[i].ID]; ok {
+			apps[i].CoUsers = &n
+		}
+	}
+
+	return apps, nil
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"go.opentelemetry.io/otel"
+)
+
+// searchConfig is the text search configuration used for app searches.
+const searchConfig = "english"
+
+// AppSearch describes a full-text search of apps.
+type AppSearch struct {
+	// Query is the search text, in the syntax accepted by websearch_to_tsquery.
+	Query string
+
+	// IncludeTools also searches the names of the tools used by the apps.
+	IncludeTools bool
+
+	// IncludeCategories also searches the names of the public categories the
+	// apps are in.
+	IncludeCategories bool
+
+	// SharedAppIDs are the IDs of the apps shared with the user, which are
+	// searched along with the public apps and the user's own apps. Only used
+	// for searches with a username.
+	SharedAppIDs []string
+}
+
+// likePattern returns a pattern for a case-insensitive substring match of the
+// text, escaping the LIKE wildcards.
+func likePattern(text string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(text) + "%"
+}
+
+// apply limits the query to the apps matching the search and adds the
+// search_rank column.
+func (s *AppSearch) apply(db GoquDatabase, a exp.IdentifierExpression, query *goqu.SelectDataset) *goqu.SelectDataset {
+	document := goqu.L(
+		"setweight(to_tsvector(?, ?), 'A') || setweight(to_tsvector(?, COALESCE(?, '')), 'B')",
+		searchConfig, a.Col("name"), searchConfig, a.Col("description"),
+	)
+	if s.IncludeTools {
+		document = goqu.L(
+			"? || setweight(to_tsvector(?, COALESCE((?), '')), 'C')",
+			document, searchConfig, toolNamesQuery(db, a),
+		)
+	}
+	if s.IncludeCategories {
+		document = goqu.L(
+			"? || setweight(to_tsvector(?, COALESCE((?), '')), 'C')",
+			document, searchConfig, publicCategoryNamesQuery(db, a),
+		)
+	}
+
+	tsquery := goqu.L("websearch_to_tsquery(?, ?)", searchConfig, s.Query)
+	rank := goqu.L("ts_rank(?, ?)", document, tsquery)
+
+	return query.
+		SelectAppend(rank.As(goqu.C("search_rank"))).
+		Where(goqu.Or(
+			goqu.L("? @@ ?", document, tsquery),
+			a.Col("name").ILike(likePattern(s.Query)),
+		))
+}
+
+// SearchApps returns the apps matching the search, best matches first. The
+// cfg.AppIDs field contains the public app IDs. Anonymous searches only return
+// public apps; searches with a username also return the user's own apps and
+// the apps shared with them.
+func (d *Database) SearchApps(ctx context.Context, cfg *AppsQueryConfig, search *AppSearch, opts ...QueryOption) ([]App, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "SearchApps")
+	defer span.End()
+
+	query := d.NewAppQuery("app search results", cfg.Username, cfg.GroupsIndex)
+
+	if cfg.Username == "" {
+		query = query.PublicOnly(cfg.AppIDs).IntegratedOnly()
+	} else {
+		query = query.WithPublicAppIDs(cfg.AppIDs).AccessibleTo(search.SharedAppIDs)
+	}
+
+	return query.
+		MatchingSearch(search).
+		RankBy(RankBySearchRank).
+		Run(ctx, opts...)
+}
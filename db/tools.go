@@ -0,0 +1,80 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// AppTool describes a tool used by one of the steps of an app.
+type AppTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Image   string `json:"image,omitempty"`
+}
+
+// AppTools is the list of tools used by an app, scanned from a JSON array.
+type AppTools []AppTool
+
+// Scan implements sql.Scanner.
+func (t *AppTools) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into AppTools", src)
+	}
+	return json.Unmarshal(data, t)
+}
+
+// Value implements driver.Valuer.
+func (t AppTools) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+// appToolsQuery returns a subquery over the tools used by the app, joined to
+// their container images.
+func appToolsQuery(db GoquDatabase, a exp.IdentifierExpression) *goqu.SelectDataset {
+	s := goqu.T("app_steps")
+	tk := goqu.T("tasks")
+	t := goqu.T("tools")
+	ci := goqu.T("container_images")
+
+	return db.From(s).
+		Join(tk, goqu.On(s.Col("task_id").Eq(tk.Col("id")))).
+		Join(t, goqu.On(tk.Col("tool_id").Eq(t.Col("id")))).
+		LeftJoin(ci, goqu.On(t.Col("container_images_id").Eq(ci.Col("id")))).
+		Where(s.Col("app_id").Eq(a.Col("id")))
+}
+
+// toolNamesQuery returns a subquery that lists the names of the tools used by
+// the app as a single string.
+func toolNamesQuery(db GoquDatabase, a exp.IdentifierExpression) *goqu.SelectDataset {
+	t := goqu.T("tools")
+	return appToolsQuery(db, a).Select(goqu.L("string_agg(?, ' ')", t.Col("name")))
+}
+
+// toolsColumn returns the tools column for an app query, which contains a JSON
+// array of the tools used by the app in step order.
+func toolsColumn(db GoquDatabase, a exp.IdentifierExpression) interface{} {
+	s := goqu.T("app_steps")
+	t := goqu.T("tools")
+	ci := goqu.T("container_images")
+
+	tool := goqu.L(
+		"json_build_object('name', ?, 'version', ?, 'image', ? || COALESCE(':' || ?, ''))",
+		t.Col("name"), t.Col("version"), ci.Col("name"), ci.Col("tag"),
+	)
+	tools := appToolsQuery(db, a).Select(goqu.L("json_agg(? ORDER BY ?)", tool, s.Col("step")))
+
+	return goqu.L("COALESCE((?), '[]')", tools).As(goqu.C("tools"))
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"go.opentelemetry.io/otel"
+)
+
+// jobWindowsTable is the alias of the subquery joined by WithJobWindows.
+var jobWindowsTable = goqu.T("job_windows")
+
+// growthRate returns an expression for the relative growth between two of the
+// job window columns. Windows without any baseline usage are treated as if
+// they had one job or user.
+func growthRate(recent, baseline string) exp.LiteralExpression {
+	s := jobWindowsTable
+	return goqu.L(
+		"(? - ?)::float / GREATEST(?, 1)",
+		s.Col(recent), s.Col(baseline), s.Col(baseline),
+	)
+}
+
+// joinJobWindows joins the number of jobs and distinct users for each app in
+// the most recent interval and in the interval before it.
+func joinJobWindows(db GoquDatabase, a exp.IdentifierExpression, query *goqu.SelectDataset, interval string) *goqu.SelectDataset {
+	j := goqu.T("jobs")
+	s := jobWindowsTable
+
+	recentStart := intervalAgo(interval)
+	baselineStart := goqu.L("now() - 2 * ?", goqu.Cast(goqu.V(interval), "INTERVAL"))
+
+	windows := db.From(j).
+		Select(
+			j.Col("app_id"),
+			goqu.L("COUNT(*) FILTER (WHERE ? >= ?)", j.Col("start_date"), recentStart).As(goqu.C("recent_job_count")),
+			goqu.L("COUNT(*) FILTER (WHERE ? < ?)", j.Col("start_date"), recentStart).As(goqu.C("baseline_job_count")),
+			goqu.L("COUNT(DISTINCT ?) FILTER (WHERE ? >= ?)", j.Col("user_id"), j.Col("start_date"), recentStart).As(goqu.C("recent_users")),
+			goqu.L("COUNT(DISTINCT ?) FILTER (WHERE ? < ?)", j.Col("user_id"), j.Col("start_date"), recentStart).As(goqu.C("baseline_users")),
+		).
+		Where(j.Col("start_date").Gte(baselineStart)).
+		GroupBy(j.Col("app_id"))
+
+	return query.
+		SelectAppend(
+			s.Col("recent_job_count").As(goqu.C("job_count")),
+			s.Col("recent_users").As(goqu.C("distinct_users")),
+			s.Col("recent_job_count"),
+			s.Col("baseline_job_count"),
+			s.Col("recent_users"),
+			s.Col("baseline_users"),
+			growthRate("recent_users", "baseline_users").As(goqu.C("growth_rate")),
+		).
+		Join(windows.As("job_windows"), goqu.On(s.Col("app_id").Eq(goqu.Cast(a.Col("id"), "TEXT")))).
+		Where(s.Col("recent_job_count").Gt(0))
+}
+
+// TrendingApps returns the public apps whose usage grew the most in the most
+// recent interval compared to the interval before it. Apps are ranked by the
+// growth in distinct users first so that one busy user can't make an app
+// trend, then by the growth in jobs. The cfg.StartDateInterval field is the
+// length of each window.
+func (d *Database) TrendingApps(ctx context.Context, cfg *AppsQueryConfig, opts ...QueryOption) ([]App, error) {
+	ctx, span := otel.Tracer(otelName).Start(ctx, "TrendingApps")
+	defer span.End()
+
+	return d.trendingAppsQuery(cfg).Run(ctx, opts...)
+}
+
+func (d *Database) trendingAppsQuery(cfg *AppsQueryConfig) *AppQuery {
+	return d.NewAppQuery("trending apps", cfg.Username, cfg.GroupsIndex).
+		PublicOnly(cfg.AppIDs).
+		IntegratedOnly().
+		WithJobWindows(cfg.StartDateInterval).
+		RankBy(RankByGrowth)
+}
+
+func (d *Database) TrendingAppsAsync(ctx context.Context, appsChan chan []App, errChan chan error, cfg *AppsQueryConfig, opts ...QueryOption) {
+	d.trendingAppsQuery(cfg).RunAsync(ctx, appsChan, errChan, opts...)
+}
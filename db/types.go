@@ -2,19 +2,35 @@ package db
 
 import (
 	"github.com/guregu/null"
+	"github.com/lib/pq"
 )
 
 type App struct {
-	ID                  string      `db:"id" json:"id"`
-	SystemID            string      `db:"system_id" json:"system_id"`
-	Name                string      `db:"name" json:"name"`
-	Description         null.String `db:"description" json:"description"`
-	WikiURL             null.String `db:"wiki_url" json:"wiki_url"`
-	IntegrationDate     null.Time   `db:"integration_date" json:"integration_date"`
-	EditedDate          null.Time   `db:"edited_date" json:"edited_date"`
-	Username            null.String `db:"username" json:"username"`
-	JobCount            null.String `db:"job_count" json:"job_count"`
-	IsFavorite          bool        `db:"is_favorite" json:"is_favorite"`
-	IsPublic            bool        `db:"is_public" json:"is_public"`
-	MostRecentStartDate null.Time   `db:"most_recent_start_date" json:"-"`
+	ID                  string           `db:"id" json:"id"`
+	SystemID            string           `db:"system_id" json:"system_id"`
+	Name                string           `db:"name" json:"name"`
+	Description         null.String      `db:"description" json:"description"`
+	WikiURL             null.String      `db:"wiki_url" json:"wiki_url"`
+	IntegrationDate     null.Time        `db:"integration_date" json:"integration_date"`
+	EditedDate          null.Time        `db:"edited_date" json:"edited_date"`
+	Username            null.String      `db:"username" json:"username"`
+	JobCount            null.String      `db:"job_count" json:"job_count"`
+	DistinctUsers       null.Int         `db:"distinct_users" json:"distinct_users"`
+	IsFavorite          bool             `db:"is_favorite" json:"is_favorite"`
+	IsPublic            bool             `db:"is_public" json:"is_public"`
+	AverageRating       null.Float       `db:"average_rating" json:"average_rating"`
+	TotalRatings        int64            `db:"total_ratings" json:"total_ratings"`
+	UserRating          null.Int         `db:"user_rating" json:"user_rating"`
+	RecentJobCount      *int64           `db:"recent_job_count" json:"recent_job_count,omitempty"`
+	BaselineJobCount    *int64           `db:"baseline_job_count" json:"baseline_job_count,omitempty"`
+	RecentUsers         *int64           `db:"recent_users" json:"recent_users,omitempty"`
+	BaselineUsers       *int64           `db:"baseline_users" json:"baseline_users,omitempty"`
+	GrowthRate          *float64         `db:"growth_rate" json:"growth_rate,omitempty"`
+	CoUsers             *int64           `db:"co_users" json:"co_users,omitempty"`
+	SearchRank          *float64         `db:"search_rank" json:"search_rank,omitempty"`
+	Categories          pq.StringArray   `db:"categories" json:"categories,omitempty"`
+	Tools               AppTools         `db:"tools" json:"tools,omitempty"`
+	Hierarchies         []HierarchyClass `db:"-" json:"hierarchies,omitempty"`
+	ChangeType          string           `db:"change_type" json:"change_type,omitempty"`
+	MostRecentStartDate null.Time        `db:"most_recent_start_date" json:"-"`
 }
@@ -48,6 +48,7 @@ const VideosFeedName = "videos"
 type PublicFeeds struct {
 	feeders map[string]DashboardFeeder
 	crons   []*cron.Cron
+	store   ItemStore
 }
 
 func NewPublicFeeds() *PublicFeeds {
@@ -58,7 +59,63 @@ func NewPublicFeeds() *PublicFeeds {
 }
 
 func (p PublicFeeds) AddFeed(ctx context.Context, name string, feeder DashboardFeeder) {
-	p.feeders[name] = feeder
+	p.feeders[name] = p.persistent(name, feeder)
+}
+
+// SetStore saves the items of every feed to the store whenever they're pulled
+// successfully. It applies to feeds added before and after it's called.
+func (p *PublicFeeds) SetStore(store ItemStore) {
+	p.store = store
+	for name, feeder := range p.feeders {
+		p.feeders[name] = p.persistent(name, feeder)
+	}
+}
+
+func (p PublicFeeds) persistent(name string, feeder DashboardFeeder) DashboardFeeder {
+	if p.store == nil {
+		return feeder
+	}
+	if pf, ok := feeder.(*persistentFeeder); ok {
+		feeder = pf.DashboardFeeder
+	}
+	return &persistentFeeder{
+		DashboardFeeder: feeder,
+		name:            name,
+		store:           p.store,
+	}
+}
+
+// LoadItems sets the items of each feed to the ones saved in the store, if
+// there is one. It's meant to be called at startup before PullItems.
+func (p PublicFeeds) LoadItems(ctx context.Context) error {
+	log := log.WithField("context", "loading feed items")
+
+	if p.store == nil {
+		return nil
+	}
+
+	stored, err := p.store.FeedItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	for name, raw := range stored {
+		feeder, ok := p.feeders[name].(*persistentFeeder)
+		if !ok {
+			continue
+		}
+
+		var items []DashboardItem
+		if err = json.Unmarshal(raw, &items); err != nil {
+			log.Errorf("ignoring the stored items for feed %s: %s", name, err)
+			continue
+		}
+
+		log.Infof("loaded %d stored items for feed %s", len(items), name)
+		feeder.restore(items)
+	}
+
+	return nil
 }
 
 func (p PublicFeeds) Names() []string {
@@ -88,8 +145,14 @@ func (p PublicFeeds) ScheduleRefreshes(ctx context.Context) error {
 	return nil
 }
 
-func (p PublicFeeds) Items(ctx context.Context, name string) []DashboardItem {
-	return p.feeders[name].Items()
+// Items returns the cached items for the named feed, or false if there isn't a
+// feed with that name.
+func (p PublicFeeds) Items(ctx context.Context, name string) ([]DashboardItem, bool) {
+	feeder, ok := p.feeders[name]
+	if !ok {
+		return nil, false
+	}
+	return feeder.Items(), true
 }
 
 func (p PublicFeeds) Marshallable(ctx context.Context) map[string][]DashboardItem {
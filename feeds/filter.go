@@ -0,0 +1,81 @@
+package feeds
+
+import (
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// itemDateLayouts are the layouts tried when parsing the dates of feed items.
+var itemDateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+}
+
+// ItemDate returns the time the item was published, or false if it can't be
+// determined.
+func ItemDate(item *DashboardItem) (time.Time, bool) {
+	for _, s := range []string{item.DateAdded, item.PublicationDate} {
+		for _, layout := range itemDateLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// ItemFilter determines which of a feed's cached items are returned. The zero
+// value returns every item.
+type ItemFilter struct {
+	// Since leaves out items published before it, along with items without a
+	// recognizable date.
+	Since time.Time
+
+	// Query leaves out items that don't contain the text in their name,
+	// description, content, or author, ignoring case.
+	Query string
+
+	// Limit is the maximum number of items to return. Values less than one
+	// mean there's no limit.
+	Limit int
+}
+
+func (f *ItemFilter) matches(item *DashboardItem) bool {
+	if !f.Since.IsZero() {
+		date, ok := ItemDate(item)
+		if !ok || date.Before(f.Since) {
+			return false
+		}
+	}
+
+	if f.Query != "" {
+		query := strings.ToLower(f.Query)
+		fields := []string{item.Name, item.Description, item.Content, item.Author}
+		if !lo.SomeBy(fields, func(field string) bool {
+			return strings.Contains(strings.ToLower(field), query)
+		}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Apply returns the items that match the filter, in their original order.
+func (f *ItemFilter) Apply(items []DashboardItem) []DashboardItem {
+	filtered := make([]DashboardItem, 0, len(items))
+	for i := range items {
+		if f.Limit > 0 && len(filtered) >= f.Limit {
+			break
+		}
+		if f.matches(&items[i]) {
+			filtered = append(filtered, items[i])
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,82 @@
+package feeds
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testItems() []DashboardItem {
+	return []DashboardItem{
+		{ID: "1", Name: "Genomics Workshop", DateAdded: "2024-03-01T12:00:00Z"},
+		{ID: "2", Name: "Maintenance", Description: "The DE will be DOWN for maintenance", PublicationDate: "Fri, 01 Mar 2024 08:00:00 +0000"},
+		{ID: "3", Name: "New Apps", Description: "Imaging tools", DateAdded: "2024-02-01T00:00:00Z"},
+		{ID: "4", Name: "Undated"},
+	}
+}
+
+func itemIDs(items []DashboardItem) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestItemDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     DashboardItem
+		expected time.Time
+		ok       bool
+	}{
+		{"RFC 3339 date added", DashboardItem{DateAdded: "2024-03-01T12:00:00Z"}, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), true},
+		{"RFC 1123Z publication date", DashboardItem{PublicationDate: "Fri, 01 Mar 2024 08:00:00 +0000"}, time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), true},
+		{"date added preferred", DashboardItem{DateAdded: "2024-03-01T12:00:00Z", PublicationDate: "Thu, 01 Feb 2024 08:00:00 +0000"}, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), true},
+		{"unparseable date added", DashboardItem{DateAdded: "yesterday", PublicationDate: "Fri, 01 Mar 2024 08:00:00 +0000"}, time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), true},
+		{"no dates", DashboardItem{}, time.Time{}, false},
+		{"unparseable dates", DashboardItem{DateAdded: "yesterday", PublicationDate: "last week"}, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, ok := ItemDate(&tt.item)
+			if ok != tt.ok {
+				t.Fatalf("expected ok to be %t, got %t", tt.ok, ok)
+			}
+			if !date.Equal(tt.expected) {
+				t.Errorf("expected %s, got %s", tt.expected, date)
+			}
+		})
+	}
+}
+
+func TestItemFilterApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   ItemFilter
+		expected []string
+	}{
+		{"zero value", ItemFilter{}, []string{"1", "2", "3", "4"}},
+		{"limit", ItemFilter{Limit: 2}, []string{"1", "2"}},
+		{"zero limit", ItemFilter{Limit: 0}, []string{"1", "2", "3", "4"}},
+		{"negative limit", ItemFilter{Limit: -1}, []string{"1", "2", "3", "4"}},
+		{"limit larger than the item count", ItemFilter{Limit: 10}, []string{"1", "2", "3", "4"}},
+		{"since", ItemFilter{Since: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)}, []string{"1", "2"}},
+		{"since an exact date", ItemFilter{Since: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}, []string{"1"}},
+		{"query in the title", ItemFilter{Query: "genomics"}, []string{"1"}},
+		{"query in the description", ItemFilter{Query: "down"}, []string{"2"}},
+		{"query ignores case", ItemFilter{Query: "IMAGING"}, []string{"3"}},
+		{"query without matches", ItemFilter{Query: "proteomics"}, []string{}},
+		{"limit applies after matching", ItemFilter{Query: "e", Limit: 1}, []string{"1"}},
+		{"combined", ItemFilter{Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Query: "apps", Limit: 5}, []string{"3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := itemIDs(tt.filter.Apply(testItems())); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected items %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package feeds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ItemStore persists the last good items of each feed so that they're
+// available at startup, even if the feeds can't be pulled. The items are
+// stored as JSON.
+type ItemStore interface {
+	// FeedItems returns the stored items, keyed by feed name.
+	FeedItems(ctx context.Context) (map[string]json.RawMessage, error)
+
+	// SaveFeedItems replaces the stored items for the named feed.
+	SaveFeedItems(ctx context.Context, name string, items json.RawMessage) error
+}
+
+// FileStore is an ItemStore that keeps the items of every feed in a single
+// JSON file.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		path: path,
+	}
+}
+
+func (f *FileStore) read() (map[string]json.RawMessage, error) {
+	stored := make(map[string]json.RawMessage)
+
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return stored, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+func (f *FileStore) FeedItems(ctx context.Context) (map[string]json.RawMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.read()
+}
+
+// SaveFeedItems writes the file to a temporary file in the same directory and
+// renames it, so the file is never left partially written.
+func (f *FileStore) SaveFeedItems(ctx context.Context, name string, items json.RawMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored, err := f.read()
+	if err != nil {
+		return err
+	}
+	stored[name] = items
+
+	b, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// persistentFeeder wraps a DashboardFeeder, saving its items to an ItemStore
+// whenever they're pulled successfully.
+type persistentFeeder struct {
+	DashboardFeeder
+	name  string
+	store ItemStore
+}
+
+// SetItems sets the items and saves them to the store. An empty list usually
+// means the feed is having problems, so it's ignored rather than replacing the
+// last good items.
+func (p *persistentFeeder) SetItems(items []DashboardItem) {
+	log := log.WithField("context", "saving feed items")
+
+	if len(items) == 0 {
+		log.Warnf("feed %s returned no items, keeping the previous items", p.name)
+		return
+	}
+
+	p.DashboardFeeder.SetItems(items)
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if err = p.store.SaveFeedItems(context.Background(), p.name, b); err != nil {
+		log.Error(err)
+	}
+}
+
+// restore sets the items loaded from the store without saving them again.
+func (p *persistentFeeder) restore(items []DashboardItem) {
+	p.DashboardFeeder.SetItems(items)
+}
+
+// The feeders call the package-level helpers with themselves, so these are
+// overridden to make sure the helpers call the wrapper's SetItems.
+func (p *persistentFeeder) PullItems(ctx context.Context) { PullItems(ctx, p) }
+func (p *persistentFeeder) ScheduleRefresh(ctx context.Context) (*cron.Cron, error) {
+	return ScheduleRefresh(ctx, p)
+}
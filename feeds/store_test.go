@@ -0,0 +1,198 @@
+package feeds
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const emptyRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel><title>News</title><link>https://example.org</link><description>News</description></channel></rss>`
+
+func mustMarshal(t *testing.T, items []DashboardItem) json.RawMessage {
+	t.Helper()
+	b, err := json.Marshal(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func storedItems(t *testing.T, store ItemStore, name string) []DashboardItem {
+	t.Helper()
+	stored, err := store.FeedItems(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, ok := stored[name]
+	if !ok {
+		return nil
+	}
+	var items []DashboardItem
+	if err = json.Unmarshal(raw, &items); err != nil {
+		t.Fatal(err)
+	}
+	return items
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "feeds.json")
+	ctx := context.Background()
+
+	news := []DashboardItem{{ID: "1", Name: "Genomics Workshop"}}
+	videos := []DashboardItem{{ID: "2", Name: "Getting Started"}}
+	updated := []DashboardItem{{ID: "3", Name: "Maintenance"}}
+
+	store := NewFileStore(path)
+	if err := store.SaveFeedItems(ctx, "news", mustMarshal(t, news)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveFeedItems(ctx, "videos", mustMarshal(t, videos)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveFeedItems(ctx, "news", mustMarshal(t, updated)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store reads what the first one wrote.
+	reopened := NewFileStore(path)
+	if got := storedItems(t, reopened, "news"); !reflect.DeepEqual(got, updated) {
+		t.Errorf("expected news items %+v, got %+v", updated, got)
+	}
+	if got := storedItems(t, reopened, "videos"); !reflect.DeepEqual(got, videos) {
+		t.Errorf("expected video items %+v, got %+v", videos, got)
+	}
+
+	// The temporary files are cleaned up.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the store file in %s, found %d entries", dir, len(entries))
+	}
+}
+
+func TestFileStoreMissingFile(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "feeds.json"))
+
+	stored, err := store.FeedItems(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("expected no stored items, got %v", stored)
+	}
+}
+
+func TestFileStoreCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feeds.json")
+	corrupt := []byte(`{"news": [`)
+	if err := os.WriteFile(path, corrupt, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	store := NewFileStore(path)
+	if _, err := store.FeedItems(ctx); err == nil {
+		t.Error("expected an error reading a corrupt file")
+	}
+
+	pf := NewPublicFeeds()
+	pf.AddFeed(ctx, "news", NewWebsiteFeed("https://example.org/feed", 10))
+	pf.SetStore(store)
+	if err := pf.LoadItems(ctx); err == nil {
+		t.Error("expected an error loading items from a corrupt file")
+	}
+	if items, _ := pf.Items(ctx, "news"); len(items) != 0 {
+		t.Errorf("expected no items, got %+v", items)
+	}
+
+	// Saving doesn't overwrite a file it can't read.
+	if err := store.SaveFeedItems(ctx, "news", mustMarshal(t, []DashboardItem{{ID: "1"}})); err == nil {
+		t.Error("expected an error saving to a corrupt file")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(corrupt) {
+		t.Errorf("expected the corrupt file to be left alone, got %s", b)
+	}
+}
+
+func TestPersistentFeederKeepsItemsOnEmptyPull(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(filepath.Join(t.TempDir(), "feeds.json"))
+
+	pf := NewPublicFeeds()
+	pf.AddFeed(ctx, "news", NewWebsiteFeed("https://example.org/feed", 10))
+	pf.SetStore(store)
+
+	feeder, ok := pf.feeders["news"].(*persistentFeeder)
+	if !ok {
+		t.Fatalf("expected a *persistentFeeder, got %T", pf.feeders["news"])
+	}
+
+	items := []DashboardItem{{ID: "1", Name: "Genomics Workshop"}}
+	feeder.SetItems(items)
+	feeder.SetItems([]DashboardItem{})
+	feeder.SetItems(nil)
+
+	if got, _ := pf.Items(ctx, "news"); !reflect.DeepEqual(got, items) {
+		t.Errorf("expected items %+v, got %+v", items, got)
+	}
+	if got := storedItems(t, store, "news"); !reflect.DeepEqual(got, items) {
+		t.Errorf("expected stored items %+v, got %+v", items, got)
+	}
+}
+
+// TestStoredItemsAtStartup follows the startup order in main: the stored items
+// are loaded before the feeds are pulled, so they're still served when the
+// first pull comes back empty or fails.
+func TestStoredItemsAtStartup(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"empty feed", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("content-type", "application/rss+xml")
+			_, _ = w.Write([]byte(emptyRSS))
+		}},
+		{"failing feed", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), "feeds.json")
+			items := []DashboardItem{{ID: "1", Name: "Genomics Workshop"}}
+			if err := NewFileStore(path).SaveFeedItems(ctx, "news", mustMarshal(t, items)); err != nil {
+				t.Fatal(err)
+			}
+
+			pf := NewPublicFeeds()
+			pf.AddFeed(ctx, "news", NewWebsiteFeed(srv.URL, 10))
+			pf.SetStore(NewFileStore(path))
+			if err := pf.LoadItems(ctx); err != nil {
+				t.Fatal(err)
+			}
+			pf.PullItems(ctx)
+
+			if got, _ := pf.Items(ctx, "news"); !reflect.DeepEqual(got, items) {
+				t.Errorf("expected items %+v, got %+v", items, got)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	_ "expvar"
 
 	"github.com/cyverse-de/dashboard-aggregator/app"
+	"github.com/cyverse-de/dashboard-aggregator/auth"
 	"github.com/cyverse-de/dashboard-aggregator/config"
 	"github.com/cyverse-de/dashboard-aggregator/db"
 	"github.com/cyverse-de/dashboard-aggregator/feeds"
@@ -33,6 +35,34 @@ type DBConfig struct {
 	Name     string
 }
 
+// newFeedStore returns the store used to persist feed items, or nil if they
+// aren't persisted.
+func newFeedStore(ctx context.Context, cfg *config.FeedsConfiguration, database *db.Database) (feeds.ItemStore, error) {
+	switch cfg.CacheType {
+	case config.FeedCacheFile:
+		log.Infof("Persisting feed items to %s", cfg.CachePath)
+		return feeds.NewFileStore(cfg.CachePath), nil
+	case config.FeedCacheDatabase:
+		log.Info("Persisting feed items to the database")
+		if cfg.CacheCreateTable {
+			if err := database.CreateFeedItemsTable(ctx); err != nil {
+				return nil, err
+			}
+			return database, nil
+		}
+		exists, err := database.FeedItemsTableExists(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New("the feed items table doesn't exist; create it or set feeds.cache.create_table")
+		}
+		return database, nil
+	default:
+		return nil, nil
+	}
+}
+
 func main() {
 	var (
 		err    error
@@ -84,12 +114,28 @@ func main() {
 	}
 	log.Info("Done connecting to the database")
 
+	database := db.New(dbconn)
+
 	ctx := context.Background()
 	pf := feeds.NewPublicFeeds()
 	pf.AddFeed(ctx, "news", feeds.NewWebsiteFeed(config.Feeds.NewsFeedURL, *itemLimit))
 	pf.AddFeed(ctx, "events", feeds.NewWebsiteFeed(config.Feeds.EventsFeedURL, *itemLimit))
 	pf.AddFeed(ctx, "videos", feeds.NewVideoFeed(config.Feeds.VideosURL, *itemLimit))
 
+	store, err := newFeedStore(ctx, config.Feeds, database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if store != nil {
+		pf.SetStore(store)
+
+		log.Info("Loading stored feed items")
+		if err = pf.LoadItems(ctx); err != nil {
+			log.Error(err)
+		}
+		log.Info("Done loading stored feed items")
+	}
+
 	log.Info("Pulling items from feeds")
 	pf.PullItems(ctx)
 	log.Info("Done pulling items from feeds")
@@ -100,8 +146,17 @@ func main() {
 	}
 	log.Info("Done scheduling feed refreshes")
 
-	database := db.New(dbconn)
-	a, err := app.New(database, pf, config)
+	var authenticator *auth.Authenticator
+	if config.Auth.Enabled {
+		log.Info("Setting up authentication")
+		authenticator, err = auth.New(ctx, config.Auth)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Info("Done setting up authentication")
+	}
+
+	a, err := app.New(database, pf, config, authenticator)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -114,6 +169,24 @@ func main() {
 	}
 	log.Info("Done setting the public group ID")
 
+	log.Info("Caching the public and featured app IDs")
+	if err = a.RefreshAppIDs(ctx); err != nil {
+		log.Error(err)
+	}
+	if _, err = a.ScheduleAppIDRefresh(ctx); err != nil {
+		log.Fatal(err)
+	}
+	log.Info("Done caching the public and featured app IDs")
+
+	log.Info("Pulling instant launches")
+	if err = a.RefreshInstantLaunches(ctx); err != nil {
+		log.Error(err)
+	}
+	if _, err = a.ScheduleInstantLaunchRefresh(ctx); err != nil {
+		log.Fatal(err)
+	}
+	log.Info("Done pulling instant launches")
+
 	ae := a.Echo()
 
 	log.Info("Starting the server")